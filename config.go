@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// configName is the file name of the config, which is looked up in the start directory first and in the home directory second.
+const configName = ".purge-deps.json"
+
+// fileConfig holds persistent defaults of flags. Flags given on the command line take precedence.
+type fileConfig struct {
+	Tools    []string `json:"tools"`    // names of the runners to use like -tools, all if empty
+	Exclude  []string `json:"exclude"`  // glob patterns of directories to skip like -exclude
+	MaxDepth *int     `json:"maxDepth"` // like -depth
+	Jobs     *int     `json:"jobs"`     // like -jobs
+	// Profiles are named sets of flag defaults for -profile, by flag name and with values as given on the command line.
+	// They take precedence over the built-in profiles of the same name.
+	Profiles map[string]map[string]string `json:"profiles"`
+}
+
+// loadConfig reads the JSON config at path. Unknown keys are rejected to catch typos.
+func loadConfig(path string) (fileConfig, error) {
+	var c fileConfig
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return c, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// findConfig returns the path of the config in dir or in the home directory - or an empty string if there is none.
+func findConfig(dir string) string {
+	dirs := []string{dir}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+	for _, d := range dirs {
+		path := filepath.Join(d, configName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// applyConfig sets the flags of the config, unless they were given explicitly.
+func applyConfig(fs *flag.FlagSet, c fileConfig) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	// -tool selects the runners just like -tools, so the tools of the config must not add to it
+	if explicit["tool"] {
+		explicit["tools"] = true
+	}
+	values := map[string][]string{
+		"exclude": c.Exclude,
+	}
+	if len(c.Tools) > 0 {
+		values["tools"] = []string{strings.Join(c.Tools, ",")}
+	}
+	if c.MaxDepth != nil {
+		values["depth"] = []string{strconv.Itoa(*c.MaxDepth)}
+	}
+	if c.Jobs != nil {
+		values["jobs"] = []string{strconv.Itoa(*c.Jobs)}
+	}
+	for name, list := range values {
+		if explicit[name] {
+			continue
+		}
+		for _, value := range list {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid value %q for flag -%s: %w", value, name, err)
+			}
+		}
+	}
+	return nil
+}
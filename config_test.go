@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	depth := 4
+	tests := []struct {
+		name    string
+		config  string
+		want    fileConfig
+		wantErr bool
+	}{
+		{
+			name:   "flags",
+			config: `{"tools": ["npm"], "maxDepth": 4}`,
+			want:   fileConfig{Tools: []string{"npm"}, MaxDepth: &depth},
+		},
+		{
+			name:   "profiles",
+			config: `{"profiles": {"ci": {"yes": "true"}}}`,
+			want:   fileConfig{Profiles: map[string]map[string]string{"ci": {"yes": "true"}}},
+		},
+		{
+			name:    "unknown key",
+			config:  `{"profile": "ci"}`,
+			wantErr: true,
+		},
+		{
+			name:    "profile with a list",
+			config:  `{"profiles": {"ci": {"tools": ["npm"]}}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "purge-config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, configName)
+			if err := ioutil.WriteFile(path, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyConfigTools(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string // runners selected by -tools and -tool
+	}{
+		{"config only", nil, []string{"npm", "cargo"}},
+		{"-tools", []string{"-tools", "composer"}, []string{"composer"}},
+		{"-tool", []string{"-tool", "go"}, []string{"go"}},
+		{"-tools and -tool", []string{"-tools", "composer", "-tool", "go"}, []string{"composer", "go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("purge-deps", flag.ContinueOnError)
+			tools := fs.String("tools", "", "")
+			var tool stringList
+			fs.Var(&tool, "tool", "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if err := applyConfig(fs, fileConfig{Tools: []string{"npm", "cargo"}}); err != nil {
+				t.Fatalf("applyConfig() = %v", err)
+			}
+			var got []string
+			for _, name := range append(strings.Split(*tools, ","), tool...) {
+				if name != "" {
+					got = append(got, name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyConfig() selected %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// stdin receives the answers to prompts.
+var stdin io.Reader = os.Stdin
+
+// confirm prints the question to stderr and reports whether the user answered yes on stdin.
+// Anything else, including an empty or missing answer, means no.
+func confirm(question string) bool {
+	fmt.Fprintf(stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{"y\n", true},
+		{"yes\n", true},
+		{" YES \n", true},
+		{"y", true},
+		{"n\n", false},
+		{"no\n", false},
+		{"\n", false},
+		{"", false},
+		{"yep\n", false},
+	}
+	oldIn, oldErr := stdin, stderr
+	defer func() { stdin, stderr = oldIn, oldErr }()
+	for _, tt := range tests {
+		stdin, stderr = strings.NewReader(tt.answer), ioutil.Discard
+		if got := confirm("Proceed?"); got != tt.want {
+			t.Errorf("confirm() with answer %q = %v, want %v", tt.answer, got, tt.want)
+		}
+	}
+}
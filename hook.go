@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// runHook runs the shell command with the given error message in the environment variable PURGE_ERROR.
+func runHook(command, msg string) error {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	cmd.Env = append(os.Environ(), "PURGE_ERROR="+msg)
+	cmd.Stdout = stderr // stdout is reserved for paths
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	return nil
+}
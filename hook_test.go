@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestOnError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook is a shell command")
+	}
+	dir, err := ioutil.TempDir("", "purge-hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	report := filepath.Join(dir, "report")
+	hook := `printf '%s' "$PURGE_ERROR" > ` + report
+	tests := []struct {
+		name  string
+		paths []string
+		code  int
+		want  string // error passed to the hook, not run if empty
+	}{
+		{"success", []string{dir}, successExitCode, ""},
+		{"failure", []string{dir, filepath.Join(dir, "missing")}, errorExitCode, "purging finished with 1 error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(report)
+			_, stderr, code := runMain(t, strings.Join(tt.paths, "\n"), "-stdin", "-yes", "-skip-cache", "-tools", "webext", "-on-error", hook)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d\n%s", code, tt.code, stderr)
+			}
+			data, err := ioutil.ReadFile(report)
+			if tt.want == "" && !os.IsNotExist(err) {
+				t.Errorf("hook ran with %q after a successful run", data)
+			}
+			if tt.want != "" && string(data) != tt.want {
+				t.Errorf("hook ran with %q, %v, want %q", data, err, tt.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,69 @@ Usage: purge-deps [/path/to/your/projects]
 If no path is provided, the current directory will be used as root directory.
 
 Flags:
-  -dry      <bool>    output found directories only - do not remove
+  -dry                      <bool>        output found directories only - do not remove
+  -show-skipped             <bool>        print a summary of skipped directories and why they were skipped
+  -report-composer-global   <bool>        print the size of Composer's global vendor directory
+  -clean-composer-global    <bool>        remove Composer's global vendor directory (includes globally installed tools)
+  -realpath                 <bool>        resolve symbolic links in printed paths
+  -js-global-all            <bool>        clear the global caches of npm, yarn and pnpm - whichever are installed
+  -reinstall                <bool>        reinstall dependencies (npm ci, composer install, cargo fetch) after cleaning a project
+  -clean-broken-symlinks    <bool>        remove symbolic links whose target does not exist after purging
+  -git-idle                 <duration>    only clean projects whose last git commit (or manifest change outside of git) is older, e.g. 90d
+  -print0                   <bool>        separate printed paths by NUL instead of newline, e.g. for xargs -0
+  -clean-build-output       <bool>        remove the build output directory declared in vite and rollup configs (defaults to dist) and of browser extensions (dist and build)
+  -cargo-clean-mode         <string>      what to clean in cargo projects: full (cargo clean), incremental (compilation caches only) or doc (target/doc)
+  -report-duplicates        <bool>        report package versions installed in more than one node_modules directory - implies -dry
+  -max-errors               <int>         abort after this many non-fatal errors (e.g. failed reinstalls) - 0 means unlimited
+  -system-caches            <bool>        also clear the download caches of system package managers (brew cleanup, apt-get clean as root)
+  -cargo-args               <string>      extra arguments for cargo clean, e.g. "--release"
+  -dotnet-args              <string>      extra arguments for dotnet clean, e.g. "-c Release"
+  -clean-reports            <bool>        also remove test and coverage reports (coverage, .nyc_output, junit.xml, debug logs) of matched projects
+  -progress                 <bool>        count all directories before purging and report the progress in percent - reads each directory twice
+  -self-test                <bool>        purge a temporary tree with fake projects of each available runner and report which runners work correctly
+  -deep                     <bool>        also purge what is slow to rebuild: the cargo registry index, pyenv virtualenvs named like a python project, Unreal Engine binaries and project-local .gradle directories
+  -sandbox                  <bool>        run clean and reinstall commands with a minimal environment: only well-known variables and absolute PATH entries are passed on
+  -go-clean-download-tmp    <bool>        remove leftover *.tmp and *.lock files of interrupted downloads from the go module cache
+  -tree                     <bool>        print a tree of the reclaimable space of all matches with sizes rolled up to their parent directories - implies -dry
+  -list-runners             <bool>        print all runners with their matched files, artifacts and availability as JSON
+  -free-space               <bool>        report the actual change of free space on the filesystem of the path next to the measured size of all removed directories
+  -by-tool                  <bool>        print the number and size of reclaimable directories per runner, the largest first - implies -dry
+  -clean-docker-context     <bool>        remove the directories listed in a .purge-docker file next to a Dockerfile
+  -strict-global            <bool>        exit with an error as soon as purging a global cache fails, instead of reporting it only
+  -skip-submodules          <bool>        don't walk into git submodules declared in .gitmodules files
+  -json-array               <bool>        print a single JSON document with the matched project directories, a summary and errors at the end instead of a path per line - buffers all matches in memory
+  -io-rate                  <string>      limit the throughput of removals, e.g. 200MB/s - removes file by file
+  -respect-git-tracked      <bool>        don't remove dependency directories which are committed to git
+  -on-error                 <string>      run this shell command once if the run fails, the error message is passed in PURGE_ERROR
+  -limit-per-tool           <int>         process at most this many projects per runner, e.g. to spread a purge over several runs - 0 means unlimited
+  -profile                  <string>      apply a named set of flag defaults, explicitly given flags take precedence: safe, aggressive or one of the profiles of the config
+  -age-histogram            <bool>        print the number and size of reclaimable directories by the age of their manifest - implies -dry
+  -depth                    <int>         descend at most this many directory levels below the path - 0 processes the path only, -1 means unlimited
+  -simulate-error-rate      <float>       debug: make this fraction (0-1) of removals fail, e.g. to test scripts handling partial failures
+  -simulate-error-seed      <int>         debug: seed of the simulated failures, the same seed fails the same removals
+  -exclude                  <pattern>     skip directories matching this glob pattern relative to the path, e.g. "archived/*" - repeatable
+  -fix-bin-links            <bool>        repair instead of purge: only remove broken symbolic links from node_modules/.bin of JS projects
+  -show-sizes               <bool>        print the reclaimed size of each project and a total to stderr - projects cleaned by an external command are measured before and after
+  -json                     <bool>        print a line of JSON per processed directory with its path, action, runner and size instead of the path only, e.g. for jq
+  -jobs                     <int>         number of projects to clean concurrently - the directory walk itself is sequential, 0 picks it for the storage of the path: the number of CPUs on SSDs, 1 on hard disks and twice as many on network mounts
+  -yes                      <bool>        don't ask for confirmation - by default the matches are listed and the purge has to be confirmed on stdin
+  -tools                    <string>      comma separated names of the runners to use, e.g. npm,cargo - all available runners by default
+  -verbose                  <bool>        log each entered directory, skipped match and runner whose tools are missing to stderr
+  -tool                     <name>        use only the runner of this name, e.g. npm - repeatable, adds to -tools and replaces the tools of the config
+  -skip-cache               <bool>        don't clear the global caches of go, composer, npm and the other tools after purging the projects
+  -cache-only               <bool>        only clear the global caches, don't walk the path
+  -go-bin                   <bool>        also remove the bin directory of go modules, which usually holds locally built tools - vendor is removed unless committed to git
+  -keep-going               <bool>        keep walking after a project or directory failed, e.g. for lack of permissions, and report all errors at the end
+  -timeout                  <duration>    kill clean commands like cargo clean after this duration, e.g. 30s - 0 means unlimited, reinstalls are never limited
+  -include                  <pattern>     only process projects matching this glob pattern relative to the path, e.g. "work/*" - repeatable, -exclude still applies
+  -since                    <duration>    only clean projects whose manifest was not modified within this duration, e.g. 720h or 30d
+  -stdin                    <bool>        read newline separated paths from stdin and purge each instead of a single path argument - requires -yes or -dry
+  -fail-fast                <bool>        with -stdin, stop at the first path which fails instead of continuing with the next
+  -no-color                 <bool>        don't color printed paths - colors are used on terminals only and disabled by the NO_COLOR environment variable, too
+  -output                   <string>      write the processed paths (or the JSON of -json and -json-array) to this file instead of stdout - it is truncated first, errors still go to stderr
+  -no-progress              <bool>        don't report scanned directories and cleaned projects on stderr every second - reported in place, if stderr is a terminal
+  -root-marker              <name>        don't descend below directories containing a file or directory of this name, e.g. vendored projects - repeatable, defaults to .git, "" disables it
+  -min-size                 <size>        only clean projects which free at least this much space, e.g. 10MB - projects of runners with clean commands are measured as a whole
 
 Exit codes:
  0=success
@@ -20,54 +82,20 @@ Try:
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/denisbrodbeck/purge-npm/purge"
 )
 
-// Walk walks all directories in the given path with a Breadth-First-Search approach
-// and cleans each matching directory.
-//
-// Assumptions:
-// If a file match is found, the associated func runs and the whole directory is finished.
-//   --> Stop walking the matched and already processed directory
-func Walk(path string, tasks []Task) error {
-	entries, err := ioutil.ReadDir(path)
-	if err != nil {
-		return fmt.Errorf("failed to read file entries of directory %q: %w", path, err)
-	}
-	// loop over files only and search for matches
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		for _, task := range tasks {
-			if task.Matches(entry.Name()) {
-				// the only output to stdout of this app is the full path of a processed match
-				fmt.Fprintln(os.Stdout, filepath.Join(path, entry.Name()))
-				// exec clean up task and bail out of this directory
-				return task.Run(filepath.Join(path, entry.Name()))
-			}
-		}
-	}
-	// loop over directories and walk into them
-	for _, entry := range entries {
-		// `file.IsDir()` check excludes strange files like symbolic links, device files or named pipes
-		// that's exactly what we need
-		if entry.IsDir() {
-			if err := Walk(filepath.Join(path, entry.Name()), tasks); err != nil {
-				// don't wrap the error - at this point all error paths are already wrapped
-				return err
-			}
-		}
-	}
-	return nil
-}
+// stderr receives the errors of the command line app, the purge writes to os.Stderr, too.
+var stderr io.Writer = os.Stderr
 
 var (
 	successExitCode    = 0
@@ -77,8 +105,118 @@ var (
 
 func main() {
 	flagDry := flag.Bool("dry", false, "output found directories only - do not remove")
-	// populate Args
+	flagShowSkipped := flag.Bool("show-skipped", false, "print a summary of skipped directories and why they were skipped")
+	flagReportComposerGlobal := flag.Bool("report-composer-global", false, "print the size of Composer's global vendor directory")
+	flagCleanComposerGlobal := flag.Bool("clean-composer-global", false, "remove Composer's global vendor directory (includes globally installed tools)")
+	flagRealpath := flag.Bool("realpath", false, "resolve symbolic links in printed paths")
+	flagJSGlobalAll := flag.Bool("js-global-all", false, "clear the global caches of npm, yarn and pnpm - whichever are installed")
+	flagReinstall := flag.Bool("reinstall", false, "reinstall dependencies (npm ci, composer install, cargo fetch) after cleaning a project")
+	flagCleanBrokenSymlinks := flag.Bool("clean-broken-symlinks", false, "remove symbolic links whose target does not exist after purging")
+	flagGitIdle := flag.String("git-idle", "", "only clean projects whose last git commit (or manifest change outside of git) is older, e.g. 90d")
+	flagPrint0 := flag.Bool("print0", false, "separate printed paths by NUL instead of newline, e.g. for xargs -0")
+	flagCleanBuildOutput := flag.Bool("clean-build-output", false, "remove the build output directory declared in vite and rollup configs (defaults to dist) and of browser extensions (dist and build)")
+	flagCargoCleanMode := flag.String("cargo-clean-mode", "full", "what to clean in cargo projects: full (cargo clean), incremental (compilation caches only) or doc (target/doc)")
+	flagReportDuplicates := flag.Bool("report-duplicates", false, "report package versions installed in more than one node_modules directory - implies -dry")
+	flagMaxErrors := flag.Int("max-errors", 0, "abort after this many non-fatal errors (e.g. failed reinstalls) - 0 means unlimited")
+	flagSystemCaches := flag.Bool("system-caches", false, "also clear the download caches of system package managers (brew cleanup, apt-get clean as root)")
+	flagCargoArgs := flag.String("cargo-args", "", "extra arguments for cargo clean, e.g. \"--release\"")
+	flagDotnetArgs := flag.String("dotnet-args", "", "extra arguments for dotnet clean, e.g. \"-c Release\"")
+	flagCleanReports := flag.Bool("clean-reports", false, "also remove test and coverage reports (coverage, .nyc_output, junit.xml, debug logs) of matched projects")
+	flagProgress := flag.Bool("progress", false, "count all directories before purging and report the progress in percent - reads each directory twice")
+	flagSelfTest := flag.Bool("self-test", false, "purge a temporary tree with fake projects of each available runner and report which runners work correctly")
+	flagDeep := flag.Bool("deep", false, "also purge what is slow to rebuild: the cargo registry index, pyenv virtualenvs named like a python project, Unreal Engine binaries and project-local .gradle directories")
+	flagSandbox := flag.Bool("sandbox", false, "run clean and reinstall commands with a minimal environment: only well-known variables and absolute PATH entries are passed on")
+	flagGoCleanDownloadTmp := flag.Bool("go-clean-download-tmp", false, "remove leftover *.tmp and *.lock files of interrupted downloads from the go module cache")
+	flagTree := flag.Bool("tree", false, "print a tree of the reclaimable space of all matches with sizes rolled up to their parent directories - implies -dry")
+	flagListRunners := flag.Bool("list-runners", false, "print all runners with their matched files, artifacts and availability as JSON")
+	flagFreeSpace := flag.Bool("free-space", false, "report the actual change of free space on the filesystem of the path next to the measured size of all removed directories")
+	flagByTool := flag.Bool("by-tool", false, "print the number and size of reclaimable directories per runner, the largest first - implies -dry")
+	flagCleanDockerContext := flag.Bool("clean-docker-context", false, "remove the directories listed in a .purge-docker file next to a Dockerfile")
+	flagStrictGlobal := flag.Bool("strict-global", false, "exit with an error as soon as purging a global cache fails, instead of reporting it only")
+	flagSkipSubmodules := flag.Bool("skip-submodules", false, "don't walk into git submodules declared in .gitmodules files")
+	flagJSONArray := flag.Bool("json-array", false, "print a single JSON document with the matched project directories, a summary and errors at the end instead of a path per line - buffers all matches in memory")
+	flagIORate := flag.String("io-rate", "", "limit the throughput of removals, e.g. 200MB/s - removes file by file")
+	flagRespectGitTracked := flag.Bool("respect-git-tracked", false, "don't remove dependency directories which are committed to git")
+	flagOnError := flag.String("on-error", "", "run this shell command once if the run fails, the error message is passed in PURGE_ERROR")
+	flagLimitPerTool := flag.Int("limit-per-tool", 0, "process at most this many projects per runner, e.g. to spread a purge over several runs - 0 means unlimited")
+	flagProfile := flag.String("profile", "", "apply a named set of flag defaults, explicitly given flags take precedence: safe, aggressive or one of the profiles of the config")
+	flagAgeHistogram := flag.Bool("age-histogram", false, "print the number and size of reclaimable directories by the age of their manifest - implies -dry")
+	flagDepth := flag.Int("depth", -1, "descend at most this many directory levels below the path - 0 processes the path only, -1 means unlimited")
+	flagSimulateErrorRate := flag.Float64("simulate-error-rate", 0, "debug: make this fraction (0-1) of removals fail, e.g. to test scripts handling partial failures")
+	flagSimulateErrorSeed := flag.Int64("simulate-error-seed", 1, "debug: seed of the simulated failures, the same seed fails the same removals")
+	var flagExclude stringList
+	flag.Var(&flagExclude, "exclude", "skip directories matching this glob pattern relative to the path, e.g. \"archived/*\" - repeatable")
+	flagFixBinLinks := flag.Bool("fix-bin-links", false, "repair instead of purge: only remove broken symbolic links from node_modules/.bin of JS projects")
+	flagShowSizes := flag.Bool("show-sizes", false, "print the reclaimed size of each project and a total to stderr - projects cleaned by an external command are measured before and after")
+	flagJSON := flag.Bool("json", false, "print a line of JSON per processed directory with its path, action, runner and size instead of the path only, e.g. for jq")
+	flagJobs := flag.Int("jobs", 0, "number of projects to clean concurrently - the directory walk itself is sequential, 0 picks it for the storage of the path: the number of CPUs on SSDs, 1 on hard disks and twice as many on network mounts")
+	flagYes := flag.Bool("yes", false, "don't ask for confirmation - by default the matches are listed and the purge has to be confirmed on stdin")
+	flagTools := flag.String("tools", "", "comma separated names of the runners to use, e.g. npm,cargo - all available runners by default")
+	flagVerbose := flag.Bool("verbose", false, "log each entered directory, skipped match and runner whose tools are missing to stderr")
+	var flagTool stringList
+	flag.Var(&flagTool, "tool", "use only the runner of this name, e.g. npm - repeatable, adds to -tools and replaces the tools of the config")
+	flagSkipCache := flag.Bool("skip-cache", false, "don't clear the global caches of go, composer, npm and the other tools after purging the projects")
+	flagCacheOnly := flag.Bool("cache-only", false, "only clear the global caches, don't walk the path")
+	flagGoBin := flag.Bool("go-bin", false, "also remove the bin directory of go modules, which usually holds locally built tools - vendor is removed unless committed to git")
+	flagKeepGoing := flag.Bool("keep-going", false, "keep walking after a project or directory failed, e.g. for lack of permissions, and report all errors at the end")
+	flagTimeout := flag.Duration("timeout", 2*time.Minute, "kill clean commands like cargo clean after this duration, e.g. 30s - 0 means unlimited, reinstalls are never limited")
+	var flagInclude stringList
+	flag.Var(&flagInclude, "include", "only process projects matching this glob pattern relative to the path, e.g. \"work/*\" - repeatable, -exclude still applies")
+	flagSince := flag.String("since", "", "only clean projects whose manifest was not modified within this duration, e.g. 720h or 30d")
+	flagStdin := flag.Bool("stdin", false, "read newline separated paths from stdin and purge each instead of a single path argument - requires -yes or -dry")
+	flagFailFast := flag.Bool("fail-fast", false, "with -stdin, stop at the first path which fails instead of continuing with the next")
+	flagNoColor := flag.Bool("no-color", false, "don't color printed paths - colors are used on terminals only and disabled by the NO_COLOR environment variable, too")
+	flagOutput := flag.String("output", "", "write the processed paths (or the JSON of -json and -json-array) to this file instead of stdout - it is truncated first, errors still go to stderr")
+	flagNoProgress := flag.Bool("no-progress", false, "don't report scanned directories and cleaned projects on stderr every second - reported in place, if stderr is a terminal")
+	var flagRootMarker stringList
+	flag.Var(&flagRootMarker, "root-marker", "don't descend below directories containing a file or directory of this name, e.g. vendored projects - repeatable, defaults to .git, \"\" disables it")
+	flagMinSize := flag.String("min-size", "", "only clean projects which free at least this much space, e.g. 10MB - projects of runners with clean commands are measured as a whole")
 	flag.Parse()
+	var c fileConfig
+	if config := findConfig(flag.Arg(0)); config != "" {
+		var err error
+		if c, err = loadConfig(config); err != nil {
+			fmt.Fprintf(stderr, "failed to apply config: %v\n", err)
+			os.Exit(errorParseExitCode)
+		}
+	}
+	if *flagProfile != "" {
+		if err := applyProfile(flag.CommandLine, *flagProfile, c.Profiles); err != nil {
+			fmt.Fprintf(stderr, "failed to apply flag -profile: %v\n", err)
+			os.Exit(errorParseExitCode)
+		}
+	}
+	// the config only provides defaults, so it is applied after explicit flags and the profile
+	if err := applyConfig(flag.CommandLine, c); err != nil {
+		fmt.Fprintf(stderr, "failed to apply config: %v\n", err)
+		os.Exit(errorParseExitCode)
+	}
+	if *flagReportDuplicates || *flagTree || *flagByTool || *flagAgeHistogram {
+		// read-only analysis
+		*flagDry = true
+	}
+
+	if *flagJSON && (*flagJSONArray || *flagPrint0) {
+		fmt.Fprintf(stderr, "failed to parse flag -json: can't be combined with -json-array or -print0\n")
+		os.Exit(errorParseExitCode)
+	}
+
+	if *flagSkipCache && *flagCacheOnly {
+		fmt.Fprintf(stderr, "failed to parse flag -cache-only: can't be combined with -skip-cache\n")
+		os.Exit(errorParseExitCode)
+	}
+	if *flagCacheOnly && *flagDry {
+		fmt.Fprintf(stderr, "failed to parse flag -cache-only: can't be combined with -dry or flags implying it\n")
+		os.Exit(errorParseExitCode)
+	}
+	if *flagJobs < 0 {
+		fmt.Fprintf(stderr, "failed to parse flag -jobs: %d is negative\n", *flagJobs)
+		os.Exit(errorParseExitCode)
+	}
+	if *flagTimeout < 0 {
+		fmt.Fprintf(stderr, "failed to parse flag -timeout: %v is negative\n", *flagTimeout)
+		os.Exit(errorParseExitCode)
+	}
 
 	// default to current directory
 	path := "."
@@ -86,186 +224,362 @@ func main() {
 	if len(args) > 0 && args[0] != "" {
 		path = args[0]
 	}
+	if *flagStdin && len(args) > 0 {
+		fmt.Fprintf(stderr, "failed to parse flag -stdin: can't be combined with a path argument\n")
+		os.Exit(errorParseExitCode)
+	}
+	if *flagStdin && *flagTree {
+		fmt.Fprintf(stderr, "failed to parse flag -stdin: can't be combined with -tree\n")
+		os.Exit(errorParseExitCode)
+	}
+	if *flagStdin && !*flagDry && !*flagYes && !*flagCacheOnly {
+		// the confirmation is read from stdin, too
+		fmt.Fprintf(stderr, "failed to parse flag -stdin: requires -yes or -dry\n")
+		os.Exit(errorParseExitCode)
+	}
 
-	// convert given path into an absolute and clean path
-	absPath, err := filepath.Abs(path)
+	switch *flagCargoCleanMode {
+	case "full", "incremental", "doc":
+	default:
+		fmt.Fprintf(stderr, "invalid value %q for flag -cargo-clean-mode: must be one of full, incremental, doc\n", *flagCargoCleanMode)
+		os.Exit(errorParseExitCode)
+	}
+	cargoArgs, err := parseArgs(*flagCargoArgs)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to parse given path %s: %v\n", path, err)
+		fmt.Fprintf(stderr, "failed to parse flag -cargo-args: %v\n", err)
 		os.Exit(errorParseExitCode)
 	}
-
-	var runners = []runner{
-		{
-			available: func() bool {
-				_, err := exec.LookPath("composer")
-				return err == nil
-			},
-			matches: func(s string) bool {
-				return s == "composer.json"
-			},
-			run: func(path string) error {
-				dir := filepath.Join(filepath.Dir(path), "vendor")
-				if err := os.RemoveAll(dir); err != nil && os.IsNotExist(err) {
-					return fmt.Errorf("failed to remove path %s: %w", dir, err)
-				}
-				return nil
-			},
-		},
-		{
-			available: func() bool {
-				_, err := exec.LookPath("npm")
-				return err == nil
-			},
-			matches: func(s string) bool {
-				return s == "package.json"
-			},
-			run: func(path string) error {
-				dir := filepath.Join(filepath.Dir(path), "node_modules")
-				if err := os.RemoveAll(dir); err != nil && os.IsNotExist(err) {
-					return fmt.Errorf("failed to remove path %s: %w", dir, err)
-				}
-				return nil
-			},
-		},
-		{
-			available: func() bool {
-				_, err := exec.LookPath(appName("cargo"))
-				return err == nil
-			},
-			matches: func(s string) bool {
-				return s == "Cargo.toml" || s == "cargo.toml"
-			},
-			run: func(path string) error {
-				cmd := exec.Command(appName("cargo"), "clean") // app will be found in PATH by `exec`
-				cmd.Dir = filepath.Dir(path)                   // set working dir
-				if err := cmd.Run(); err != nil {
-					return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
-				}
-				return nil
-			},
-		},
-		{
-			available: func() bool {
-				_, err := exec.LookPath(appName("dotnet"))
-				return err == nil
-			},
-			matches: func(s string) bool {
-				return strings.HasSuffix(strings.ToLower(s), ".csproj") || strings.HasSuffix(strings.ToLower(s), ".sln")
-			},
-			run: func(path string) error {
-				cmd := exec.Command(appName("dotnet"), "clean", "--nologo") // app will be found in PATH by `exec`
-				cmd.Dir = filepath.Dir(path)                                // set working dir
-				if out, err := cmd.CombinedOutput(); err != nil {
-					// this one fails often, because only dotnet core projects are supported
-					fmt.Fprintf(os.Stderr, "failed to run command %q: %v\n%s\n", cmd.String(), err, string(out))
-					return nil
-				}
-				return nil
-			},
-		},
-	}
-	if *flagDry {
-		// replace all ops with a default print path func when flag --dry is set
-		for key := range runners {
-			runners[key].run = func(path string) error {
-				fmt.Fprintln(os.Stdout, path)
-				return nil
+	dotnetArgs, err := parseArgs(*flagDotnetArgs)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to parse flag -dotnet-args: %v\n", err)
+		os.Exit(errorParseExitCode)
+	}
+	gitIdle, err := parseAge(*flagGitIdle)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to parse flag -git-idle: %v\n", err)
+		os.Exit(errorParseExitCode)
+	}
+	since, err := parseAge(*flagSince)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to parse flag -since: %v\n", err)
+		os.Exit(errorParseExitCode)
+	}
+	minSize, err := parseSize(*flagMinSize)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to parse flag -min-size: %v\n", err)
+		os.Exit(errorParseExitCode)
+	}
+	ioRate, err := parseRate(*flagIORate)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to parse flag -io-rate: %v\n", err)
+		os.Exit(errorParseExitCode)
+	}
+	if *flagSimulateErrorRate < 0 || *flagSimulateErrorRate > 1 {
+		fmt.Fprintf(stderr, "failed to parse flag -simulate-error-rate: %v is not between 0 and 1\n", *flagSimulateErrorRate)
+		os.Exit(errorParseExitCode)
+	}
+	rootMarkers := []string{".git"}
+	if len(flagRootMarker) > 0 {
+		// an empty marker only disables the default
+		rootMarkers = nil
+		for _, marker := range flagRootMarker {
+			if marker != "" {
+				rootMarkers = append(rootMarkers, marker)
 			}
 		}
 	}
 
-	var tasks = []Task{}
-	for _, r := range runners {
-		// only keep runners which we have the proper dev tools installed for
-		if r.Available() {
-			tasks = append(tasks, r)
+	// convert given paths into absolute and clean paths
+	var roots []string
+	var invalidRoots []error // paths read from stdin, which can't be walked
+	if *flagStdin {
+		paths, err := readPaths(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to parse flag -stdin: %v\n", err)
+			os.Exit(errorParseExitCode)
 		}
+		for _, p := range paths {
+			root, err := resolveRoot(p)
+			if err != nil {
+				if *flagFailFast {
+					fmt.Fprintln(stderr, err)
+					os.Exit(errorExitCode)
+				}
+				invalidRoots = append(invalidRoots, err)
+				continue
+			}
+			roots = append(roots, root)
+		}
+		for _, err := range invalidRoots {
+			fmt.Fprintln(stderr, err)
+		}
+		if len(roots) == 0 {
+			fmt.Fprintf(stderr, "failed to parse flag -stdin: no valid paths given\n")
+			os.Exit(errorParseExitCode)
+		}
+	} else {
+		root, err := resolveRoot(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			os.Exit(errorParseExitCode)
+		}
+		roots = append(roots, root)
 	}
-
-	// no tasks no worries
-	if len(tasks) == 0 {
-		fmt.Fprintf(os.Stderr, "no valid package managers found (tried cargo, composer, npm)\n")
-		os.Exit(errorParseExitCode)
-	}
-
-	if err := Walk(absPath, tasks); err != nil {
-		fmt.Fprintf(os.Stderr, "purging failed with an error: %v\n", err)
-		os.Exit(errorExitCode)
+	if *flagJobs == 0 {
+		// the first path stands in for all others
+		storage := storageOf(roots[0])
+		*flagJobs = storage.jobs()
+		if *flagVerbose {
+			fmt.Fprintf(stderr, "cleaning %d %s concurrently on %s storage\n", *flagJobs, plural(*flagJobs, "project", "projects"), storage)
+		}
 	}
-	if !*flagDry {
-		if err := clearCachesGo(); err != nil {
-			fmt.Fprintf(os.Stderr, "purging go cache failed with an error: %v\n", err)
+	if *flagListRunners {
+		if err := purge.ListRunners(os.Stdout); err != nil {
+			fmt.Fprintf(stderr, "listing runners failed with an error: %v\n", err)
 			os.Exit(errorExitCode)
 		}
-		if err := clearCachesComposer(); err != nil {
-			fmt.Fprintf(os.Stderr, "purging composer cache failed with an error: %v\n", err)
+		os.Exit(0)
+	}
+	if *flagSelfTest {
+		// runs the unmodified runners against a temporary tree, so it ignores --dry
+		if !purge.SelfTest(stderr) {
 			os.Exit(errorExitCode)
 		}
-		if err := clearCachesNpm(); err != nil {
-			fmt.Fprintf(os.Stderr, "purging npm cache failed with an error: %v\n", err)
-			os.Exit(errorExitCode)
+		os.Exit(0)
+	}
+	var tools []string
+	for _, name := range append(strings.Split(*flagTools, ","), flagTool...) {
+		if name = strings.TrimSpace(name); name != "" {
+			tools = append(tools, name)
+		}
+	}
+	known := map[string]bool{}
+	for _, name := range purge.RunnerNames() {
+		known[name] = true
+	}
+	for _, name := range tools {
+		if !known[name] {
+			fmt.Fprintf(stderr, "failed to parse flag -tool: unknown runner %q, valid names are: %s\n", name, strings.Join(purge.RunnerNames(), ", "))
+			os.Exit(errorParseExitCode)
 		}
 	}
-}
 
-// Task represents a runner which executes a function when a valid match is found.
-type Task interface {
-	Available() bool
-	Matches(string) bool
-	Run(string) error
-}
+	// closeOutput flushes and closes the file of -output, if any
+	var output io.Writer = os.Stdout
+	closeOutput := func() {}
+	if *flagOutput != "" {
+		file, err := os.Create(*flagOutput)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to parse flag -output: %v\n", err)
+			os.Exit(errorParseExitCode)
+		}
+		output = file
+		closeOutput = func() {
+			err := file.Sync()
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "failed to write output file %s: %v\n", *flagOutput, err)
+			}
+		}
+	}
+	// abort ends a failed run, which triggers the -on-error hook
+	abort := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		fmt.Fprintln(stderr, msg)
+		closeOutput()
+		if *flagOnError != "" {
+			if err := runHook(*flagOnError, msg); err != nil {
+				fmt.Fprintf(stderr, "running -on-error hook failed with an error: %v\n", err)
+			}
+		}
+		os.Exit(errorExitCode)
+	}
 
-func clearCachesGo() error {
-	cmd := exec.Command(appName("go"), "clean", "-cache")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	cfg := purge.Config{
+		Roots:                roots,
+		Tools:                tools,
+		Stdout:               output,
+		Stderr:               os.Stderr,
+		Dry:                  *flagDry,
+		Verbose:              *flagVerbose,
+		Depth:                *flagDepth,
+		Jobs:                 *flagJobs,
+		KeepGoing:            *flagKeepGoing,
+		FailFast:             *flagFailFast,
+		MaxErrors:            *flagMaxErrors,
+		Timeout:              *flagTimeout,
+		Exclude:              flagExclude,
+		Include:              flagInclude,
+		RootMarkers:          rootMarkers,
+		GitIdle:              gitIdle,
+		Since:                since,
+		MinSize:              minSize,
+		LimitPerTool:         *flagLimitPerTool,
+		SkipSubmodules:       *flagSkipSubmodules,
+		RespectGitTracked:    *flagRespectGitTracked,
+		Deep:                 *flagDeep,
+		CargoCleanMode:       *flagCargoCleanMode,
+		CargoArgs:            cargoArgs,
+		DotnetArgs:           dotnetArgs,
+		GoBin:                *flagGoBin,
+		CleanBuildOutput:     *flagCleanBuildOutput,
+		CleanDockerContext:   *flagCleanDockerContext,
+		CleanReports:         *flagCleanReports,
+		Reinstall:            *flagReinstall,
+		Sandbox:              *flagSandbox,
+		IORate:               ioRate,
+		SimulateErrorRate:    *flagSimulateErrorRate,
+		SimulateErrorSeed:    *flagSimulateErrorSeed,
+		Realpath:             *flagRealpath,
+		Print0:               *flagPrint0,
+		Color:                useColor(*flagNoColor) && *flagOutput == "",
+		JSON:                 *flagJSON,
+		JSONArray:            *flagJSONArray,
+		ShowSkipped:          *flagShowSkipped,
+		ShowSizes:            *flagShowSizes,
+		Progress:             *flagProgress,
+		ProgressInPlace:      isTerminal(os.Stderr),
+		Tree:                 *flagTree,
+		ByTool:               *flagByTool,
+		AgeHistogram:         *flagAgeHistogram,
+		FreeSpace:            *flagFreeSpace,
+		ReportDuplicates:     *flagReportDuplicates,
+		FixBinLinks:          *flagFixBinLinks,
+		SkipCache:            *flagSkipCache,
+		CacheOnly:            *flagCacheOnly,
+		JSGlobalAll:          *flagJSGlobalAll,
+		SystemCaches:         *flagSystemCaches,
+		StrictGlobal:         *flagStrictGlobal,
+		CleanBrokenSymlinks:  *flagCleanBrokenSymlinks,
+		ReportComposerGlobal: *flagReportComposerGlobal,
+		CleanComposerGlobal:  *flagCleanComposerGlobal,
+		GoCleanDownloadTmp:   *flagGoCleanDownloadTmp,
 	}
-	cmd = exec.Command(appName("go"), "clean", "-modcache")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	if !*flagYes {
+		cfg.Confirm = confirm
 	}
-	cmd = exec.Command(appName("go"), "clean", "-testcache")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	// redirected stderr, e.g. a log file of a cron job, gets no progress lines
+	if !*flagNoProgress && isTerminal(os.Stderr) {
+		cfg.ProgressInterval = time.Second
 	}
-	return nil
+	err = purge.Run(notifyInterrupt(), cfg)
+	var taskErr *purge.TaskError
+	switch {
+	case errors.Is(err, purge.ErrNoTasks):
+		fmt.Fprintln(stderr, err)
+		os.Exit(errorParseExitCode)
+	case errors.Is(err, purge.ErrAborted):
+		fmt.Fprintln(stderr, err)
+		os.Exit(errorExitCode)
+	case errors.As(err, &taskErr):
+		abort("purging %s failed with an error of runner %s: %v", taskErr.Path, taskErr.Tool, taskErr.Err)
+	case err != nil:
+		abort("%v", err)
+	case len(invalidRoots) > 0:
+		// the paths read from stdin which could not be walked fail the run in the end
+		abort("purging finished with %d %s", len(invalidRoots), plural(len(invalidRoots), "error", "errors"))
+	}
+	closeOutput()
 }
 
-func clearCachesComposer() error {
-	cmd := exec.Command("composer", "--no-interaction", "clear-cache") // app will be found in PATH by `exec`
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+// parseAge parses a duration like time.ParseDuration, but additionally accepts days (e.g. 90d) and weeks (e.g. 2w).
+// An empty string parses as zero.
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
 	}
-	return nil
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) {
+			if n < 0 {
+				return 0, fmt.Errorf("negative duration %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %q", s)
+	}
+	return d, nil
 }
 
-func clearCachesNpm() error {
-	cmd := exec.Command("npm", "cache", "clean", "--force")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+// parseRate parses a throughput like `200MB/s` or `1GiB` into bytes per second, the `/s` suffix is optional.
+func parseRate(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
 	}
-	return nil
+	n, err := parseSize(strings.TrimSuffix(strings.TrimSpace(s), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: expected a positive number with an optional unit, e.g. 200MB/s", s)
+	}
+	return n, nil
 }
 
-func appName(name string) string {
-	if runtime.GOOS == "windows" {
-		return name + ".exe"
+// parseSize parses a size like `10MB` into bytes.
+// Units are B, KB, MB, GB (powers of 1000) and KiB, MiB, GiB (powers of 1024). An empty string parses as zero.
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	num := strings.TrimSpace(s)
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"B", 1},
+	}
+	factor := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(num, u.suffix) {
+			num, factor = strings.TrimSuffix(num, u.suffix), u.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q: expected a positive number with an optional unit, e.g. 10MB", s)
 	}
-	return name
+	return int64(n * float64(factor)), nil
 }
 
-type runner struct {
-	available func() bool
-	matches   func(string) bool
-	run       func(string) error
+// shellMetacharacters are rejected in extra command arguments.
+// Commands are executed directly instead of through a shell, so quoting, expansions
+// or redirections would be passed on literally instead of doing what the user expects.
+const shellMetacharacters = "|&;<>()$`\\\"' \t*?[]{}~!#"
+
+// parseArgs splits a string of extra command arguments at white space.
+func parseArgs(s string) ([]string, error) {
+	args := strings.Fields(s)
+	for _, arg := range args {
+		if i := strings.IndexAny(arg, shellMetacharacters); i >= 0 {
+			return nil, fmt.Errorf("argument %q contains the shell metacharacter %q, but arguments are passed on without a shell", arg, arg[i])
+		}
+	}
+	return args, nil
 }
 
-func (r runner) Available() bool {
-	return r.available()
+// plural returns the singular form of a noun for a count of one and the plural form otherwise.
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
 }
-func (r runner) Matches(name string) bool {
-	return r.matches(name)
+
+// stringList is a flag which accumulates the values of all its occurrences.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
 }
-func (r runner) Run(path string) error {
-	return r.run(path)
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
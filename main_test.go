@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMain runs main with the arguments after `--`, if the test binary was started by runMain.
+func TestMain(m *testing.M) {
+	if os.Getenv("PURGE_DEPS_TEST_MAIN") == "1" {
+		for i, arg := range os.Args {
+			if arg == "--" {
+				os.Args = append([]string{"purge-deps"}, os.Args[i+1:]...)
+				break
+			}
+		}
+		main()
+		os.Exit(successExitCode)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the app with the arguments in a child process and returns its output and exit code.
+func runMain(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^$", "--"}, args...)...)
+	// no config of the home directory, no progress lines and no colors
+	cmd.Env = append(os.Environ(), "PURGE_DEPS_TEST_MAIN=1", "HOME="+os.TempDir(), "NO_COLOR=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	code := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), code
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"", []string{}, false},
+		{"--release", []string{"--release"}, false},
+		{"  -c   Release ", []string{"-c", "Release"}, false},
+		{"--target-dir=/tmp/target", []string{"--target-dir=/tmp/target"}, false},
+		{"--release; rm -rf /", nil, true},
+		{"-c 'Release'", nil, true},
+		{"$HOME", nil, true},
+		{"--features a|b", nil, true},
+		{"target/*", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseArgs(tt.in)
+			if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseArgs(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulateErrors(t *testing.T) {
+	tests := []struct {
+		rate    string
+		code    int
+		kept    int // web-ext-artifacts directories left
+		summary string
+	}{
+		{"0", successExitCode, 0, ""},
+		{"1", errorExitCode, 3, "purging finished with 3 errors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rate, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "purge-simulate")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for _, name := range []string{"a", "b", "c"} {
+				ext := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Join(ext, "web-ext-artifacts"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				manifest := `{"manifest_version": 2, "name": "` + name + `", "version": "1.0"}`
+				if err := ioutil.WriteFile(filepath.Join(ext, "manifest.json"), []byte(manifest), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, stderr, code := runMain(t, "", "-yes", "-skip-cache", "-tools", "webext", "-keep-going", "-simulate-error-rate", tt.rate, dir)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d\n%s", code, tt.code, stderr)
+			}
+			kept, _ := filepath.Glob(filepath.Join(dir, "*", "web-ext-artifacts"))
+			if len(kept) != tt.kept {
+				t.Errorf("kept %d web-ext-artifacts directories, want %d", len(kept), tt.kept)
+			}
+			if tt.summary != "" && !strings.Contains(stderr, tt.summary) {
+				t.Errorf("stderr = %q, want %q", stderr, tt.summary)
+			}
+			if n := strings.Count(stderr, "simulated failure"); tt.summary != "" && n != 3 {
+				t.Errorf("stderr reported %d simulated failures, want 3\n%s", n, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+)
+
+// profiles are named sets of flag defaults, which explicitly given flags override.
+var profiles = map[string]map[string]string{
+	// leave anything alone which might still be in use or is managed elsewhere
+	"safe": {
+		"git-idle":            "90d",
+		"respect-git-tracked": "true",
+		"skip-submodules":     "true",
+	},
+	// reclaim as much space as possible, including caches which are slow to rebuild
+	"aggressive": {
+		"deep":                  "true",
+		"js-global-all":         "true",
+		"clean-build-output":    "true",
+		"clean-reports":         "true",
+		"clean-broken-symlinks": "true",
+		"go-clean-download-tmp": "true",
+	},
+}
+
+// applyProfile sets the flags of the named profile, unless they were given explicitly.
+// The profile is looked up in the profiles of the config first and in the built-in profiles second.
+func applyProfile(fs *flag.FlagSet, name string, custom map[string]map[string]string) error {
+	profile, ok := custom[name]
+	if !ok {
+		profile, ok = profiles[name]
+	}
+	if !ok {
+		known := map[string]bool{}
+		for n := range custom {
+			known[n] = true
+		}
+		for n := range profiles {
+			known[n] = true
+		}
+		names := make([]string, 0, len(known))
+		for n := range known {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q, available profiles: %v", name, names)
+	}
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for key, value := range profile {
+		if explicit[key] {
+			continue
+		}
+		if err := fs.Set(key, value); err != nil {
+			return fmt.Errorf("invalid value %q for flag -%s of profile %q: %w", value, key, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// newProfileFlags returns a flag set with all flags of the built-in profiles and a few more.
+func newProfileFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	for _, name := range []string{
+		"respect-git-tracked", "skip-submodules", "deep", "js-global-all", "clean-build-output",
+		"clean-reports", "clean-broken-symlinks", "go-clean-download-tmp", "yes", "skip-cache",
+	} {
+		fs.Bool(name, false, "")
+	}
+	fs.String("git-idle", "", "")
+	fs.Int("jobs", 1, "")
+	return fs
+}
+
+// flagValues returns the values of all flags of fs which differ from their defaults.
+func flagValues(fs *flag.FlagSet) map[string]string {
+	values := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Value.String() != f.DefValue {
+			values[f.Name] = f.Value.String()
+		}
+	})
+	return values
+}
+
+func TestApplyProfile(t *testing.T) {
+	custom := map[string]map[string]string{
+		"ci":   {"yes": "true", "jobs": "8"},
+		"safe": {"git-idle": "30d"},
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		profile string
+		custom  map[string]map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "safe",
+			profile: "safe",
+			want:    map[string]string{"git-idle": "90d", "respect-git-tracked": "true", "skip-submodules": "true"},
+		},
+		{
+			name:    "aggressive",
+			profile: "aggressive",
+			want: map[string]string{
+				"deep": "true", "js-global-all": "true", "clean-build-output": "true",
+				"clean-reports": "true", "clean-broken-symlinks": "true", "go-clean-download-tmp": "true",
+			},
+		},
+		{
+			name:    "explicit flags override",
+			args:    []string{"-git-idle", "7d", "-skip-submodules=false"},
+			profile: "safe",
+			want:    map[string]string{"git-idle": "7d", "respect-git-tracked": "true"},
+		},
+		{
+			name:    "profile of the config",
+			profile: "ci",
+			custom:  custom,
+			want:    map[string]string{"yes": "true", "jobs": "8"},
+		},
+		{
+			name:    "config replaces a built-in profile",
+			profile: "safe",
+			custom:  custom,
+			want:    map[string]string{"git-idle": "30d"},
+		},
+		{
+			name:    "built-in profile next to a config",
+			profile: "aggressive",
+			custom:  custom,
+			want: map[string]string{
+				"deep": "true", "js-global-all": "true", "clean-build-output": "true",
+				"clean-reports": "true", "clean-broken-symlinks": "true", "go-clean-download-tmp": "true",
+			},
+		},
+		{
+			name:    "unknown profile",
+			profile: "ci",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			profile: "broken",
+			custom:  map[string]map[string]string{"broken": {"jobs": "many"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newProfileFlags()
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := applyProfile(fs, tt.profile, tt.custom)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyProfile() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := flagValues(fs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyProfile() set %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
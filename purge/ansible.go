@@ -0,0 +1,84 @@
+package purge
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// reAnsibleRequirements matches the top-level keys or list entries of an ansible-galaxy requirements file
+var reAnsibleRequirements = regexp.MustCompile(`(?m)^(roles|collections)\s*:|^-\s+(src|name)\s*:`)
+
+// isAnsibleName reports whether the file name could belong to an ansible requirements file or molecule scenario.
+func isAnsibleName(name string) bool {
+	switch name {
+	case "requirements.yml", "requirements.yaml", "molecule.yml":
+		return true
+	}
+	return false
+}
+
+// isAnsibleProject reports whether the file at path is an ansible-galaxy requirements file
+// or the configuration of a molecule scenario in `molecule/<scenario>/molecule.yml`.
+func isAnsibleProject(path string) bool {
+	if filepath.Base(path) == "molecule.yml" {
+		return filepath.Base(filepath.Dir(filepath.Dir(path))) == "molecule"
+	}
+	f, err := fileSystem.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head, err := ioutil.ReadAll(io.LimitReader(f, 64*1024))
+	if err != nil {
+		return false
+	}
+	return reAnsibleRequirements.Match(head)
+}
+
+// removeAnsibleCaches removes the local ansible and molecule caches of the project of the file at path.
+func removeAnsibleCaches(path string) error {
+	dir := filepath.Dir(path)
+	if filepath.Base(path) == "molecule.yml" {
+		// molecule/<scenario>/molecule.yml
+		dir = filepath.Dir(filepath.Dir(dir))
+	}
+	for _, name := range []string{".ansible", ".molecule"} {
+		p := filepath.Join(dir, name)
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// ansibleHome returns ansible's home directory, which may be overridden by `ANSIBLE_HOME`.
+func ansibleHome() (string, error) {
+	if dir := os.Getenv("ANSIBLE_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".ansible"), nil
+}
+
+// clearCachesAnsible removes ansible's temporary files and the galaxy server cache.
+// Installed roles and collections are left alone, as playbooks depend on them.
+func clearCachesAnsible() error {
+	home, err := ansibleHome()
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"tmp", "galaxy_cache"} {
+		p := filepath.Join(home, name)
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
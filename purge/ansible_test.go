@@ -0,0 +1,98 @@
+package purge
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsAnsibleProject(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		want     bool
+	}{
+		{"collections", "requirements.yml", "collections:\n  - name: community.general\n", true},
+		{"roles", "requirements.yaml", "roles:\n  - src: geerlingguy.docker\n", true},
+		{"role list", "requirements.yml", "- src: geerlingguy.docker\n  version: 4.1.0\n", true},
+		{"pip requirements", "requirements.yml", "dependencies:\n  - numpy\n", false},
+		{"molecule scenario", "molecule/default/molecule.yml", "driver:\n  name: docker\n", true},
+		{"molecule elsewhere", "test/default/molecule.yml", "driver:\n  name: docker\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{tt.file})
+			defer cleanup()
+			path := filepath.Join(dir, filepath.FromSlash(tt.file))
+			if err := ioutil.WriteFile(path, []byte(tt.contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := isAnsibleProject(path); got != tt.want {
+				t.Errorf("isAnsibleProject() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveAnsibleCaches(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{
+			name:  "requirements",
+			files: []string{"requirements.yml", ".ansible/collections/", ".molecule/", "roles/web/"},
+			want:  []string{"requirements.yml", "roles/", "roles/web/"},
+		},
+		{
+			name:  "molecule scenario",
+			files: []string{"molecule/default/molecule.yml", ".ansible/tmp/", ".molecule/", "molecule/default/.ansible/"},
+			want:  []string{"molecule/", "molecule/default/", "molecule/default/.ansible/", "molecule/default/molecule.yml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			if err := removeAnsibleCaches(filepath.Join(dir, filepath.FromSlash(tt.files[0]))); err != nil {
+				t.Fatalf("removeAnsibleCaches() = %v", err)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removeAnsibleCaches() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClearCachesAnsible(t *testing.T) {
+	tests := []struct {
+		name        string
+		ansibleHome string // ANSIBLE_HOME below the temporary directory
+		want        []string
+	}{
+		{"home directory", "", []string{"custom/", "custom/galaxy_cache/", "custom/tmp/", "home/", "home/.ansible/", "home/.ansible/collections/"}},
+		{"ANSIBLE_HOME", "custom", []string{"custom/", "home/", "home/.ansible/", "home/.ansible/collections/", "home/.ansible/galaxy_cache/", "home/.ansible/tmp/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"home/.ansible/tmp/", "home/.ansible/galaxy_cache/", "home/.ansible/collections/", "custom/tmp/", "custom/galaxy_cache/"})
+			defer cleanup()
+			ansibleHome := ""
+			if tt.ansibleHome != "" {
+				ansibleHome = filepath.Join(dir, tt.ansibleHome)
+			}
+			defer testSetenv(t, "ANSIBLE_HOME", ansibleHome)()
+			defer testSetenv(t, "HOME", filepath.Join(dir, "home"))()
+			defer testSetenv(t, "USERPROFILE", filepath.Join(dir, "home"))()
+			if err := clearCachesAnsible(); err != nil {
+				t.Fatalf("clearCachesAnsible() = %v", err)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clearCachesAnsible() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
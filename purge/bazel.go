@@ -0,0 +1,114 @@
+package purge
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// bazelMarkers are the files at the root of a Bazel workspace.
+var bazelMarkers = []string{"WORKSPACE", "WORKSPACE.bazel", "MODULE.bazel"}
+
+// isBazelMarker reports whether the file name marks the root of a Bazel workspace.
+func isBazelMarker(name string) bool {
+	for _, marker := range bazelMarkers {
+		if name == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanBazel runs `bazel clean` in the workspace of the file at path.
+// Without bazel the outputs behind the convenience symlinks of the workspace are removed instead.
+func cleanBazel(path string) error {
+	dir := filepath.Dir(path)
+	if _, err := exec.LookPath(appName("bazel")); err != nil {
+		return removeBazelOutputs(dir)
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+	cmd := exec.CommandContext(ctx, appName("bazel"), "clean")
+	cmd.Dir = dir
+	cmd.Env = commandEnv
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to run command %q in %s: %w\n%s", cmd.String(), dir, commandError(ctx, err), string(out))
+	}
+	return nil
+}
+
+// removeBazelOutputs removes the bazel-* symlinks in the workspace dir along with their targets.
+// The links point into the output base outside of the workspace, so removing the links alone frees nothing.
+// Targets outside of an output base are kept, though.
+// All targets are resolved first, as the links point into each other's targets, e.g. bazel-out into bazel-<workspace>.
+func removeBazelOutputs(dir string) error {
+	links, err := globFS(filepath.Join(dir, "bazel-*"))
+	if err != nil {
+		return fmt.Errorf("failed to find bazel outputs in %s: %w", dir, err)
+	}
+	var targets []string
+	for _, link := range links {
+		info, err := fileSystem.Lstat(link)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			// only bazel creates the links, a real directory of that name is part of the sources
+			continue
+		}
+		target, err := filepath.EvalSymlinks(link)
+		if err != nil {
+			// the output base is gone already, e.g. after `bazel clean --expunge`
+			targets = append(targets, link)
+			continue
+		}
+		// a link of that name may have been created by hand, only what bazel built is ever removed
+		if !isBazelOutput(target) {
+			logf("skipping %s: points to %s, which is not inside a bazel output base", link, target)
+			continue
+		}
+		targets = append(targets, target, link)
+	}
+	for _, p := range targets {
+		// bazel marks its outputs read-only
+		if err := makeWritable(p); err != nil {
+			return err
+		}
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// isBazelOutput reports whether path lies below the execroot of a bazel output base,
+// which bazel lays out as <output user root>/_bazel_<user>/<hash of the workspace>/execroot/<workspace>.
+func isBazelOutput(path string) bool {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "_bazel_") && i+3 < len(parts) && parts[i+2] == "execroot" {
+			return true
+		}
+	}
+	return false
+}
+
+// makeWritable adds write permissions for the owner to all directories below path, so their contents can be removed.
+// Symbolic links are not followed.
+func makeWritable(path string) error {
+	err := walkFS(path, func(p string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || info.Mode()&0200 != 0 {
+			return nil
+		}
+		return os.Chmod(p, info.Mode()|0200)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to make directory %s writable: %w", path, err)
+	}
+	return nil
+}
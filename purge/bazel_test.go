@@ -0,0 +1,76 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCleanBazel(t *testing.T) {
+	execroot := "cache/_bazel_user/0123abcd/execroot/ws"
+	files := []string{
+		"ws/WORKSPACE", "ws/src/BUILD", "ws/bazel-docs/index.md",
+		execroot + "/bazel-out/k8-fastbuild/bin/app",
+		execroot + "/bazel-out/k8-fastbuild/testlogs/test.log",
+		"elsewhere/notes.txt",
+	}
+	// the convenience symlinks of bazel point into the output base, or nowhere after it was removed
+	links := map[string]string{
+		"ws/bazel-ws":       execroot,
+		"ws/bazel-out":      execroot + "/bazel-out",
+		"ws/bazel-bin":      execroot + "/bazel-out/k8-fastbuild/bin",
+		"ws/bazel-testlogs": "cache/_bazel_user/0123abcd/execroot/gone",
+		"ws/bazel-mine":     "elsewhere",
+	}
+	untouched := []string{
+		"cache/", "cache/_bazel_user/", "cache/_bazel_user/0123abcd/", "cache/_bazel_user/0123abcd/execroot/",
+		execroot + "/", execroot + "/bazel-out/", execroot + "/bazel-out/k8-fastbuild/",
+		execroot + "/bazel-out/k8-fastbuild/bin/", execroot + "/bazel-out/k8-fastbuild/bin/app",
+		execroot + "/bazel-out/k8-fastbuild/testlogs/", execroot + "/bazel-out/k8-fastbuild/testlogs/test.log",
+		"elsewhere/", "elsewhere/notes.txt",
+		"ws/", "ws/WORKSPACE", "ws/bazel-bin", "ws/bazel-docs/", "ws/bazel-docs/index.md",
+		"ws/bazel-mine", "ws/bazel-out", "ws/bazel-testlogs", "ws/bazel-ws", "ws/src/", "ws/src/BUILD",
+	}
+	tests := []struct {
+		name  string
+		tools map[string]string
+		calls []string
+		want  []string
+	}{
+		{"bazel clean", map[string]string{"bazel": ""}, []string{"bazel clean"}, untouched},
+		{"without bazel", nil, nil, []string{
+			"cache/", "cache/_bazel_user/", "cache/_bazel_user/0123abcd/", "cache/_bazel_user/0123abcd/execroot/",
+			"elsewhere/", "elsewhere/notes.txt",
+			"ws/", "ws/WORKSPACE", "ws/bazel-docs/", "ws/bazel-docs/index.md", "ws/bazel-mine", "ws/src/", "ws/src/BUILD",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := testTools(t, tt.tools)
+			defer restore()
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			for link, target := range links {
+				if err := os.Symlink(filepath.Join(dir, filepath.FromSlash(target)), filepath.Join(dir, filepath.FromSlash(link))); err != nil {
+					t.Skip(err)
+				}
+			}
+			// bazel marks its outputs read-only
+			bin := filepath.Join(dir, filepath.FromSlash(execroot), "bazel-out", "k8-fastbuild", "bin")
+			if err := os.Chmod(bin, 0555); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Chmod(bin, 0755)
+			if err := cleanBazel(filepath.Join(dir, "ws", "WORKSPACE")); err != nil {
+				t.Fatalf("cleanBazel() = %v", err)
+			}
+			if got := calls(); !reflect.DeepEqual(got, tt.calls) {
+				t.Errorf("cleanBazel() ran %q, want %q", got, tt.calls)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cleanBazel() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
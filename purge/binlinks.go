@@ -0,0 +1,47 @@
+package purge
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// binLinkFixer removes dangling symbolic links from node_modules/.bin,
+// which partial npm operations leave behind, without touching the installed packages.
+type binLinkFixer struct {
+	out   io.Writer
+	dry   bool
+	fixed int // number of removed links
+}
+
+// fix removes the broken links of the node_modules/.bin directory of the project at path.
+func (f *binLinkFixer) fix(path string) error {
+	dir := filepath.Join(filepath.Dir(path), "node_modules", ".bin")
+	entries, err := fileSystem.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file entries of directory %q: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		link := filepath.Join(dir, entry.Name())
+		if _, err := fileSystem.Stat(link); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		fmt.Fprintf(f.out, "broken link %s\n", link)
+		f.fixed++
+		if f.dry {
+			continue
+		}
+		if err := fileSystem.Remove(link); err != nil {
+			return fmt.Errorf("failed to remove broken symbolic link %s: %w", link, err)
+		}
+	}
+	return nil
+}
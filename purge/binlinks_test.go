@@ -0,0 +1,59 @@
+package purge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFixBinLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		dry  bool
+		kept []string
+	}{
+		{"fix", false, []string{"node_modules/.bin/", "node_modules/.bin/cli", "node_modules/.bin/script", "node_modules/pkg/", "node_modules/pkg/cli.js", "package.json"}},
+		{"dry run", true, []string{"node_modules/.bin/", "node_modules/.bin/cli", "node_modules/.bin/gone", "node_modules/.bin/script", "node_modules/pkg/", "node_modules/pkg/cli.js", "package.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"package.json", "node_modules/pkg/cli.js", "node_modules/.bin/script"})
+			defer cleanup()
+			bin := filepath.Join(dir, "node_modules", ".bin")
+			if err := os.Symlink(filepath.Join("..", "pkg", "cli.js"), filepath.Join(bin, "cli")); err != nil {
+				t.Skip(err)
+			}
+			if err := os.Symlink(filepath.Join("..", "removed", "cli.js"), filepath.Join(bin, "gone")); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			f := &binLinkFixer{out: &out, dry: tt.dry}
+			if err := f.fix(filepath.Join(dir, "package.json")); err != nil {
+				t.Fatalf("fix() = %v", err)
+			}
+			if want := "broken link " + filepath.Join(bin, "gone") + "\n"; f.fixed != 1 || out.String() != want {
+				t.Errorf("fix() fixed %d links and printed %q, want 1 and %q", f.fixed, out.String(), want)
+			}
+			var kept []string
+			for _, name := range testFiles(t, dir) {
+				if name != "node_modules/" {
+					kept = append(kept, name)
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("fix() kept %q, want %q", kept, tt.kept)
+			}
+		})
+	}
+}
+
+func TestFixBinLinksWithoutBin(t *testing.T) {
+	dir, cleanup := testTree(t, []string{"package.json"})
+	defer cleanup()
+	f := &binLinkFixer{out: &bytes.Buffer{}}
+	if err := f.fix(filepath.Join(dir, "package.json")); err != nil || f.fixed != 0 {
+		t.Errorf("fix() = %v and fixed %d links, want nil and 0", err, f.fixed)
+	}
+}
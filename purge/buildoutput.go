@@ -0,0 +1,102 @@
+package purge
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultBuildOutput is the output directory of vite and most rollup setups if none is configured.
+const defaultBuildOutput = "dist"
+
+var (
+	// e.g. `build: { outDir: 'public/build' }` in vite.config.js
+	reOutDir = regexp.MustCompile("\\boutDir\\s*:\\s*['\"`]([^'\"`]+)['\"`]")
+	// e.g. `output: { dir: 'build' }` in rollup.config.js
+	reOutputDir = regexp.MustCompile("\\bdir\\s*:\\s*['\"`]([^'\"`]+)['\"`]")
+	// e.g. `output: { file: 'build/bundle.js' }` in rollup.config.js
+	reOutputFile = regexp.MustCompile("\\bfile\\s*:\\s*['\"`]([^'\"`]+)['\"`]")
+	// the start of the `build` block of a vite config
+	reBuildBlock = regexp.MustCompile("\\bbuild\\s*:\\s*\\{")
+	// the start of the `output` block of a rollup config, an object or an array of them
+	reOutputBlock = regexp.MustCompile("\\boutput\\s*:\\s*[{\\[]")
+)
+
+// isBuildConfig reports whether the file name belongs to a vite or rollup config.
+func isBuildConfig(name string) bool {
+	for _, prefix := range []string{"vite.config.", "rollup.config."} {
+		if strings.HasPrefix(name, prefix) {
+			switch strings.TrimPrefix(name, prefix) {
+			case "js", "cjs", "mjs", "ts", "cts", "mts":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildOutputDir returns the output directory declared in the vite or rollup config with the given file name.
+// Only `build.outDir` counts in vite configs and only `dir` and `file` of the `output` block in rollup configs,
+// as plugin options often use the same keys for sources.
+// Parsing javascript with regular expressions is a best effort only,
+// so the default output directory is returned whenever no literal declaration is found.
+func buildOutputDir(name string, config []byte) string {
+	if strings.HasPrefix(name, "vite.config.") {
+		if m := reOutDir.FindSubmatch(block(config, reBuildBlock)); m != nil {
+			return string(m[1])
+		}
+		return defaultBuildOutput
+	}
+	output := block(config, reOutputBlock)
+	if m := reOutputDir.FindSubmatch(output); m != nil {
+		return string(m[1])
+	}
+	if m := reOutputFile.FindSubmatch(output); m != nil {
+		if dir := filepath.Dir(string(m[1])); dir != "." {
+			return dir
+		}
+	}
+	return defaultBuildOutput
+}
+
+// block returns the contents of the first object or array in config which start matches,
+// up to its closing bracket - or nil, if there is none. Brackets within strings aren't told apart.
+func block(config []byte, start *regexp.Regexp) []byte {
+	loc := start.FindIndex(config)
+	if loc == nil {
+		return nil
+	}
+	depth := 0
+	for i := loc[1] - 1; i < len(config); i++ {
+		switch config[i] {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return config[loc[1]:i]
+			}
+		}
+	}
+	return config[loc[1]:]
+}
+
+// removeBuildOutput removes the output directory declared in the vite or rollup config at path.
+// Output directories outside of the project directory are never removed.
+func removeBuildOutput(path string) error {
+	config, err := fileSystem.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read build config %s: %w", path, err)
+	}
+	out := filepath.Clean(filepath.FromSlash(buildOutputDir(filepath.Base(path), config)))
+	if filepath.IsAbs(out) || out == "." || out == ".." || strings.HasPrefix(out, ".."+string(filepath.Separator)) {
+		fmt.Fprintf(stderr, "skipping build output %q of %s: not inside the project directory\n", out, path)
+		return nil
+	}
+	dir := filepath.Join(filepath.Dir(path), out)
+	if err := removeAll(dir); err != nil {
+		return fmt.Errorf("failed to remove path %s: %w", dir, err)
+	}
+	return nil
+}
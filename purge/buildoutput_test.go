@@ -0,0 +1,29 @@
+package purge
+
+import "testing"
+
+func TestBuildOutputDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		config string
+		want   string
+	}{
+		{"vite default", "vite.config.js", "export default {}", "dist"},
+		{"vite outDir", "vite.config.ts", "export default { build: { outDir: 'public/build' } }", "public/build"},
+		{"vite plugin dir", "vite.config.js", "export default { plugins: [icons({ dir: 'src' })] }", "dist"},
+		{"vite outDir outside build", "vite.config.js", "export default { plugins: [p({ outDir: 'src' })], build: {} }", "dist"},
+		{"rollup dir", "rollup.config.js", "export default { output: { dir: 'build' } }", "build"},
+		{"rollup file", "rollup.config.mjs", "export default { output: { file: 'lib/bundle.js' } }", "lib"},
+		{"rollup file next to config", "rollup.config.js", "export default { output: { file: 'bundle.js' } }", "dist"},
+		{"rollup output array", "rollup.config.js", "export default { output: [{ file: 'out/a.js' }, { file: 'out/b.js' }] }", "out"},
+		{"rollup plugin dir", "rollup.config.js", "export default { plugins: [copy({ dir: 'src' })], output: { format: 'es' } }", "dist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildOutputDir(tt.file, []byte(tt.config)); got != tt.want {
+				t.Errorf("buildOutputDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package purge
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// removeBundlerVendor removes the gems bundler installed into the Ruby project of the Gemfile at path.
+// Projects which install their gems system-wide have neither directory, which is fine.
+func removeBundlerVendor(path string) error {
+	for _, name := range []string{filepath.Join("vendor", "bundle"), ".bundle"} {
+		p := filepath.Join(filepath.Dir(path), name)
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// clearCachesBundler removes all but the latest version of each globally installed gem.
+// `bundle clean` works within a project only, so this asks rubygems directly, which comes with every bundler installation.
+func clearCachesBundler() error {
+	cmd := exec.Command("gem", "cleanup")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	return nil
+}
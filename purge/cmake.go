@@ -0,0 +1,35 @@
+package purge
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// cmakeBuildDirs are the build directories of CMake projects: the conventional out-of-source build directory
+// and the default build directories of CLion.
+var cmakeBuildDirs = []string{"build", "cmake-build-debug", "cmake-build-release"}
+
+// removeCMakeBuild removes the build directories next to the CMakeLists.txt file at path.
+// A build directory is only removed, if CMake configured it, as `build` holds sources in some projects.
+func removeCMakeBuild(path string) error {
+	for _, name := range cmakeBuildDirs {
+		dir := filepath.Join(filepath.Dir(path), name)
+		if _, err := fileSystem.Stat(dir); err != nil {
+			continue
+		}
+		if keepsCMakeBuild(dir) {
+			logf("skipping %s: no CMakeCache.txt", dir)
+			continue
+		}
+		if err := removeAll(dir); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// keepsCMakeBuild reports whether the build directory dir is kept, as CMake didn't configure it.
+func keepsCMakeBuild(dir string) bool {
+	_, err := fileSystem.Stat(filepath.Join(dir, "CMakeCache.txt"))
+	return err != nil
+}
@@ -0,0 +1,51 @@
+package purge
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRemoveCMakeBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{
+			name:  "marker present",
+			files: []string{"CMakeLists.txt", "build/CMakeCache.txt", "build/app.o"},
+			want:  []string{"CMakeLists.txt"},
+		},
+		{
+			name:  "marker absent",
+			files: []string{"CMakeLists.txt", "build/main.c"},
+			want:  []string{"CMakeLists.txt", "build/", "build/main.c"},
+		},
+		{
+			name: "ide builds",
+			files: []string{
+				"CMakeLists.txt", "build/main.c",
+				"cmake-build-debug/CMakeCache.txt", "cmake-build-release/CMakeCache.txt",
+			},
+			want: []string{"CMakeLists.txt", "build/", "build/main.c"},
+		},
+		{
+			name:  "no builds",
+			files: []string{"CMakeLists.txt", "src/main.c"},
+			want:  []string{"CMakeLists.txt", "src/", "src/main.c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			if err := removeCMakeBuild(filepath.Join(dir, "CMakeLists.txt")); err != nil {
+				t.Fatalf("removeCMakeBuild() = %v", err)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removeCMakeBuild() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
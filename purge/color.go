@@ -0,0 +1,18 @@
+package purge
+
+import ()
+
+// ANSI escape sequences of the colors of printed paths.
+const (
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// colorize wraps the path in the color of its action: red for removals, yellow for dry runs.
+func colorize(path string, dry bool) string {
+	if dry {
+		return colorYellow + path + colorReset
+	}
+	return colorRed + path + colorReset
+}
@@ -0,0 +1,30 @@
+/*
+Package purge finds the dependency and build directories of projects below a path and removes them,
+along with the global caches of the package managers. It is the library behind the purge-deps command line app.
+
+Each Task matches the manifest of a project, e.g. package.json, and cleans the project next to it.
+DefaultTasks returns the tasks of all package managers installed on this system:
+
+	err := purge.Walk(context.Background(), "/home/luke/code", purge.DefaultTasks())
+
+Run does everything the command line app does, including the reports and the clean up of the global caches afterwards:
+
+	err := purge.Run(ctx, purge.Config{
+		Roots:     []string{"/home/luke/code"},
+		Tools:     []string{"npm", "cargo"},
+		Dry:       true,
+		Depth:     -1,
+		SkipCache: true,
+	})
+
+The failure of a task in a project is a *TaskError, which names the tool and the project:
+
+	var taskErr *purge.TaskError
+	if errors.As(err, &taskErr) {
+		fmt.Println(taskErr.Tool, taskErr.Path)
+	}
+
+Runs and walks change package level state, e.g. the writers of Config, so concurrent calls of Run and Walk
+wait for each other.
+*/
+package purge
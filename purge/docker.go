@@ -0,0 +1,53 @@
+package purge
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dockerPurgeFile lists the directories to remove next to a Dockerfile, one per line.
+const dockerPurgeFile = ".purge-docker"
+
+// hasDockerPurgeFile reports whether the Dockerfile at path comes with a list of directories to remove.
+func hasDockerPurgeFile(path string) bool {
+	_, err := fileSystem.Stat(filepath.Join(filepath.Dir(path), dockerPurgeFile))
+	return err == nil
+}
+
+// removeDockerContext removes the directories listed in the .purge-docker file next to the Dockerfile at path.
+// Empty lines and lines starting with `#` are ignored, directories outside of the project directory are never removed.
+func removeDockerContext(path string) error {
+	list := filepath.Join(filepath.Dir(path), dockerPurgeFile)
+	f, err := fileSystem.Open(list)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", list, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out := filepath.Clean(filepath.FromSlash(line))
+		if filepath.IsAbs(out) || out == "." || out == ".." || strings.HasPrefix(out, ".."+string(filepath.Separator)) {
+			fmt.Fprintf(stderr, "skipping %q of %s: not inside the project directory\n", line, list)
+			continue
+		}
+		dir := filepath.Join(filepath.Dir(path), out)
+		if err := removeAll(dir); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", dir, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", list, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package purge
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRemoveDockerContext(t *testing.T) {
+	all := []string{"app/", "app/Dockerfile", "app/dist/", "app/dist/app", "app/target/", "app/target/release/", "keep/"}
+	tests := []struct {
+		name    string
+		list    string // contents of .purge-docker, none if empty
+		want    []string
+		skipped int
+	}{
+		{"no list", "", all, 0},
+		{"listed directories", "# image only\ndist\n\n  target/release  \n", []string{"app/", "app/Dockerfile", "app/target/", "keep/"}, 0},
+		{"outside of the project", "../keep\n/etc\n.\n", all, 3},
+		{"missing directory", "build\n", all, 0},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"keep/", "app/Dockerfile", "app/dist/app", "app/target/release/"})
+			defer cleanup()
+			list := filepath.Join(dir, "app", dockerPurgeFile)
+			if tt.list != "" {
+				if err := ioutil.WriteFile(list, []byte(tt.list), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var out bytes.Buffer
+			stderr = &out
+			if err := removeDockerContext(filepath.Join(dir, "app", "Dockerfile")); err != nil {
+				t.Fatalf("removeDockerContext() = %v", err)
+			}
+			var got []string
+			for _, name := range testFiles(t, dir) {
+				if name != "app/"+dockerPurgeFile {
+					got = append(got, name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removeDockerContext() kept %q, want %q", got, tt.want)
+			}
+			if n := strings.Count(out.String(), "not inside the project directory"); n != tt.skipped {
+				t.Errorf("removeDockerContext() skipped %d entries, want %d:\n%s", n, tt.skipped, out.String())
+			}
+		})
+	}
+}
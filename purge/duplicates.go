@@ -0,0 +1,134 @@
+package purge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dupReport collects the packages installed in many node_modules directories
+// to find identical package versions which are installed more than once.
+type dupReport struct {
+	packages map[string]*dupPackage // by name@version
+	dirs     int                    // number of scanned node_modules directories
+}
+
+// dupPackage is a single package version and the number of its installed copies.
+type dupPackage struct {
+	id     string // name@version
+	size   int64  // size of a single copy
+	copies int
+}
+
+// add scans the top-level packages of the node_modules directory of the project at path.
+func (r *dupReport) add(path string) error {
+	dir := filepath.Join(filepath.Dir(path), "node_modules")
+	entries, err := fileSystem.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file entries of directory %q: %w", dir, err)
+	}
+	r.dirs++
+	for _, entry := range entries {
+		// skip .bin, .cache and other non-packages
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if !strings.HasPrefix(entry.Name(), "@") {
+			if err := r.addPackage(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+			continue
+		}
+		// scoped packages live one level deeper, e.g. node_modules/@babel/core
+		scope := filepath.Join(dir, entry.Name())
+		scoped, err := fileSystem.ReadDir(scope)
+		if err != nil {
+			return fmt.Errorf("failed to read file entries of directory %q: %w", scope, err)
+		}
+		for _, s := range scoped {
+			if s.IsDir() {
+				if err := r.addPackage(filepath.Join(scope, s.Name())); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *dupReport) addPackage(dir string) error {
+	data, err := fileSystem.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		// not a package
+		return nil
+	}
+	var manifest struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.Name == "" || manifest.Version == "" {
+		return nil
+	}
+	id := manifest.Name + "@" + manifest.Version
+	if r.packages == nil {
+		r.packages = map[string]*dupPackage{}
+	}
+	pkg, ok := r.packages[id]
+	if !ok {
+		// copies of the same version are expected to have the same size, so measure only once
+		size, err := dirSize(dir)
+		if err != nil {
+			return err
+		}
+		pkg = &dupPackage{id: id, size: size}
+		r.packages[id] = pkg
+	}
+	pkg.copies++
+	return nil
+}
+
+// print writes the total duplicated size and the packages wasting the most space.
+func (r *dupReport) print(out io.Writer) {
+	dups := []*dupPackage{}
+	var wasted int64
+	for _, pkg := range r.packages {
+		if pkg.copies > 1 {
+			dups = append(dups, pkg)
+			wasted += pkg.wasted()
+		}
+	}
+	if len(dups) == 0 {
+		fmt.Fprintf(out, "no duplicate packages found in %d node_modules %s\n", r.dirs, plural(r.dirs, "directory", "directories"))
+		return
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].wasted() != dups[j].wasted() {
+			return dups[i].wasted() > dups[j].wasted()
+		}
+		return dups[i].id < dups[j].id
+	})
+	fmt.Fprintf(out, "%d package %s installed more than once in %d node_modules directories, duplicating %s\n",
+		len(dups), plural(len(dups), "version is", "versions are"), r.dirs, formatBytes(wasted))
+	const top = 10
+	for i, pkg := range dups {
+		if i == top {
+			fmt.Fprintf(out, "  ... and %d more\n", len(dups)-top)
+			break
+		}
+		fmt.Fprintf(out, "  %s: %d copies, %s duplicated\n", pkg.id, pkg.copies, formatBytes(pkg.wasted()))
+	}
+	fmt.Fprintln(out, "a content-addressable package store like pnpm keeps a single copy of each package version")
+}
+
+// wasted returns the size of all but one copy.
+func (p *dupPackage) wasted() int64 {
+	return p.size * int64(p.copies-1)
+}
@@ -0,0 +1,104 @@
+package purge
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPackage installs the package name@version with an index.js of size bytes into the node_modules directory of project.
+func testPackage(t *testing.T, project, name, version string, size int) {
+	t.Helper()
+	dir := filepath.Join(project, "node_modules", filepath.FromSlash(name))
+	manifest := fmt.Sprintf(`{"name":%q,"version":%q}`, name, version)
+	files := map[string]string{
+		"package.json": manifest,
+		"index.js":     strings.Repeat("x", size-len(manifest)),
+	}
+	for file, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDupReport(t *testing.T) {
+	type pkg struct {
+		name, version string
+		size          int
+	}
+	tests := []struct {
+		name     string
+		projects [][]pkg
+		wasted   int64
+		printed  string
+	}{
+		{
+			name: "no duplicates",
+			projects: [][]pkg{
+				{{"lodash", "4.17.21", 500}},
+				{{"lodash", "4.17.20", 500}},
+			},
+			printed: "no duplicate packages found in 2 node_modules directories",
+		},
+		{
+			name: "shared versions",
+			projects: [][]pkg{
+				{{"lodash", "4.17.21", 500}, {"@babel/core", "7.12.0", 300}, {"left-pad", "1.3.0", 100}},
+				{{"lodash", "4.17.21", 500}, {"@babel/core", "7.12.0", 300}, {"left-pad", "1.2.0", 100}},
+				{{"lodash", "4.17.21", 500}},
+			},
+			wasted:  2*500 + 300,
+			printed: "2 package versions are installed more than once in 3 node_modules directories, duplicating 1.3 KiB",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files []string
+			for i, pkgs := range tt.projects {
+				files = append(files, fmt.Sprintf("app%d/package.json", i))
+				for _, p := range pkgs {
+					files = append(files, fmt.Sprintf("app%d/node_modules/%s/", i, p.name))
+				}
+			}
+			// not packages
+			files = append(files, "app0/node_modules/.bin/", "app0/node_modules/.cache/lodash/package.json", "app1/node_modules/notes/")
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			r := &dupReport{}
+			for i, pkgs := range tt.projects {
+				project := filepath.Join(dir, fmt.Sprintf("app%d", i))
+				for _, p := range pkgs {
+					testPackage(t, project, p.name, p.version, p.size)
+				}
+				if err := r.add(filepath.Join(project, "package.json")); err != nil {
+					t.Fatalf("add() = %v", err)
+				}
+			}
+			var wasted int64
+			for _, p := range r.packages {
+				wasted += p.wasted()
+			}
+			if wasted != tt.wasted {
+				t.Errorf("wasted = %d, want %d", wasted, tt.wasted)
+			}
+			var out bytes.Buffer
+			r.print(&out)
+			if got := strings.SplitN(out.String(), "\n", 2)[0]; got != tt.printed {
+				t.Errorf("print() = %q, want %q", got, tt.printed)
+			}
+		})
+	}
+}
+
+func TestDupReportMissing(t *testing.T) {
+	dir, cleanup := testTree(t, []string{"package.json"})
+	defer cleanup()
+	r := &dupReport{}
+	if err := r.add(filepath.Join(dir, "package.json")); err != nil || r.dirs != 0 {
+		t.Errorf("add() = %v and scanned %d directories, want none", err, r.dirs)
+	}
+}
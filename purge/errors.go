@@ -0,0 +1,38 @@
+package purge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// errorList aggregates the errors of a walk which kept going after failures.
+type errorList []error
+
+func (l errorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d %s occurred:\n\t%s", len(l), plural(len(l), "error", "errors"), strings.Join(msgs, "\n\t"))
+}
+
+// Unwrap returns the individual errors, so callers can inspect each of them.
+func (l errorList) Unwrap() []error {
+	return l
+}
+
+// TaskError is the failure of a runner in a single project.
+type TaskError struct {
+	Tool string // name of the runner, e.g. cargo
+	Path string // directory of the project
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("runner %s failed in %s: %v", e.Tool, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error, e.g. to check for errors.Is(err, os.ErrPermission).
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,67 @@
+package purge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTaskError(t *testing.T) {
+	err := &TaskError{Tool: "cargo", Path: "/code/app", Err: fmt.Errorf("failed to remove path /code/app/target: %w", os.ErrPermission)}
+	if want := "runner cargo failed in /code/app: failed to remove path /code/app/target: permission denied"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("errors.Is(%v, os.ErrPermission) = false", err)
+	}
+	var taskErr *TaskError
+	if wrapped := fmt.Errorf("purging failed with an error: %w", err); !errors.As(wrapped, &taskErr) || taskErr != err {
+		t.Errorf("errors.As(%v) = %v", wrapped, taskErr)
+	}
+}
+
+func TestWalkTaskErrors(t *testing.T) {
+	dir, cleanup := testTree(t, []string{"app/package.json", "cli/Cargo.toml", "web/package.json"})
+	defer cleanup()
+	var tasks []Task
+	for _, r := range []runner{testDeps(), testTarget()} {
+		r.run = func(path string) error {
+			return fmt.Errorf("failed to remove path %s: %w", path, os.ErrPermission)
+		}
+		tasks = append(tasks, r)
+	}
+	tests := []struct {
+		name      string
+		keepGoing bool
+		want      []TaskError // without the underlying errors
+	}{
+		{"first error", false, []TaskError{{Tool: "deps", Path: "app"}}},
+		{"keep going", true, []TaskError{{Tool: "deps", Path: "app"}, {Tool: "target", Path: "cli"}, {Tool: "deps", Path: "web"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &walker{tasks: tasks, root: dir, out: ioutil.Discard, maxDepth: -1, keepGoing: tt.keepGoing}
+			err := w.walk(dir, 0)
+			errs := w.failures
+			if !tt.keepGoing {
+				errs = []error{err}
+			}
+			var got []TaskError
+			for _, err := range errs {
+				var taskErr *TaskError
+				if !errors.As(err, &taskErr) || !errors.Is(err, os.ErrPermission) {
+					t.Fatalf("walk() failed with %v, want a TaskError", err)
+				}
+				rel, _ := filepath.Rel(dir, taskErr.Path)
+				got = append(got, TaskError{Tool: taskErr.Tool, Path: filepath.ToSlash(rel)})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walk() failed with %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package purge_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/denisbrodbeck/purge-npm/purge"
+)
+
+func ExampleRun() {
+	root, err := ioutil.TempDir("", "purge-example")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(root)
+	// a browser extension with its packaged builds
+	ext := filepath.Join(root, "extension")
+	if err := os.MkdirAll(filepath.Join(ext, "web-ext-artifacts"), 0755); err != nil {
+		panic(err)
+	}
+	manifest := `{"manifest_version": 3, "name": "example", "version": "1.0"}`
+	if err := ioutil.WriteFile(filepath.Join(ext, "manifest.json"), []byte(manifest), 0644); err != nil {
+		panic(err)
+	}
+
+	var out bytes.Buffer
+	err = purge.Run(context.Background(), purge.Config{
+		Roots:     []string{root},
+		Tools:     []string{"webext"},
+		Stdout:    &out,
+		Stderr:    ioutil.Discard,
+		Dry:       true,
+		Depth:     -1,
+		SkipCache: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+	// each processed match is printed on a line of its own
+	for _, path := range strings.Fields(out.String()) {
+		rel, _ := filepath.Rel(root, path)
+		fmt.Println(filepath.ToSlash(rel))
+	}
+	// Output: extension/manifest.json
+}
+
+func ExampleRun_noTasks() {
+	err := purge.Run(context.Background(), purge.Config{
+		Roots:  []string{os.TempDir()},
+		Tools:  []string{"no-such-tool"},
+		Stderr: ioutil.Discard,
+	})
+	fmt.Println(errors.Is(err, purge.ErrNoTasks))
+	// Output: true
+}
@@ -0,0 +1,142 @@
+package purge
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// FileSystem abstracts the file system operations of a walk, the runners and the removal of directories,
+// e.g. to run against an in-memory file system in tests.
+type FileSystem interface {
+	ReadDir(dirname string) ([]os.FileInfo, error)
+	Stat(name string) (os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	Open(name string) (io.ReadCloser, error)
+	ReadFile(filename string) ([]byte, error)
+	Remove(name string) error
+	RemoveAll(path string) error
+}
+
+// osFileSystem is the FileSystem of the operating system.
+type osFileSystem struct{}
+
+func (osFileSystem) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(dirname)
+}
+func (osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+func (osFileSystem) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(name)
+}
+func (osFileSystem) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+func (osFileSystem) ReadFile(filename string) ([]byte, error) {
+	return ioutil.ReadFile(filename)
+}
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+func (osFileSystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// fileSystem is used by all walks, runners and removals, Run sets it from Config.FileSystem.
+var fileSystem FileSystem = osFileSystem{}
+
+// walkFS walks the file tree rooted at root like filepath.Walk, but on fileSystem.
+func walkFS(root string, fn filepath.WalkFunc) error {
+	info, err := fileSystem.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkFSDir(root, info, fn)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walkFSDir walks path like walkFS, its entries are visited in lexical order.
+func walkFSDir(path string, info os.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+	entries, err := fileSystem.ReadDir(path)
+	err1 := fn(path, info, err)
+	// an unreadable directory is reported once, its entries are skipped unless fn aborts the walk
+	if err != nil || err1 != nil {
+		return err1
+	}
+	for _, entry := range entries {
+		err := walkFSDir(filepath.Join(path, entry.Name()), entry, fn)
+		if err != nil && (!entry.IsDir() || err != filepath.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
+
+// globFS returns the names of all files matching pattern like filepath.Glob, but on fileSystem.
+// Like filepath.Glob, it ignores I/O errors and only fails on malformed patterns.
+func globFS(pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !hasGlobMeta(pattern) {
+		if _, err := fileSystem.Lstat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+	dir, file := filepath.Split(pattern)
+	if dir == "" {
+		dir = "."
+	} else if len(dir) > 1 {
+		dir = strings.TrimSuffix(dir, string(filepath.Separator))
+	}
+	if !hasGlobMeta(dir) {
+		return globFSDir(dir, file, nil), nil
+	}
+	if dir == pattern {
+		return nil, filepath.ErrBadPattern
+	}
+	dirs, err := globFS(dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, d := range dirs {
+		matches = globFSDir(d, file, matches)
+	}
+	return matches, nil
+}
+
+// globFSDir appends the entries of dir matching pattern to matches.
+func globFSDir(dir, pattern string, matches []string) []string {
+	entries, err := fileSystem.ReadDir(dir)
+	if err != nil {
+		return matches
+	}
+	for _, entry := range entries {
+		if ok, _ := filepath.Match(pattern, entry.Name()); ok {
+			matches = append(matches, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return matches
+}
+
+// hasGlobMeta reports whether path contains any of the special characters of filepath.Match.
+func hasGlobMeta(path string) bool {
+	magic := `*?[\`
+	if runtime.GOOS == "windows" {
+		magic = `*?[`
+	}
+	return strings.ContainsAny(path, magic)
+}
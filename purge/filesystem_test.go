@@ -0,0 +1,383 @@
+package purge
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memFileInfo describes an entry of a memFS.
+type memFileInfo struct {
+	name string
+	dir  bool
+	size int64
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memFileInfo) IsDir() bool        { return i.dir }
+func (i memFileInfo) Sys() interface{}   { return nil }
+func (i memFileInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// memFile is an entry of a memFS, a directory or a file with its contents.
+type memFile struct {
+	dir  bool
+	data []byte
+}
+
+// memFS is an in-memory FileSystem of the paths it maps to their entries.
+type memFS map[string]memFile
+
+// newMemFS returns a memFS with the empty files below root and all their parent directories.
+// Names ending with a slash are directories.
+func newMemFS(root string, files []string) memFS {
+	fs := memFS{root: {dir: true}}
+	for _, name := range files {
+		fs.add(filepath.Join(root, filepath.FromSlash(name)), memFile{dir: strings.HasSuffix(name, "/")})
+	}
+	return fs
+}
+
+// add adds the entry at path and all its missing parent directories.
+func (fs memFS) add(path string, f memFile) {
+	fs[path] = f
+	for dir := filepath.Dir(path); !fs[dir].dir; dir = filepath.Dir(dir) {
+		fs[dir] = memFile{dir: true}
+	}
+}
+
+func (fs memFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	if !fs[dirname].dir {
+		return nil, &os.PathError{Op: "readdir", Path: dirname, Err: os.ErrNotExist}
+	}
+	var entries []os.FileInfo
+	for p, f := range fs {
+		if filepath.Dir(p) == dirname && p != dirname {
+			entries = append(entries, memFileInfo{name: filepath.Base(p), dir: f.dir, size: int64(len(f.data))})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+func (fs memFS) Stat(name string) (os.FileInfo, error) {
+	f, ok := fs[name]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return memFileInfo{name: filepath.Base(name), dir: f.dir, size: int64(len(f.data))}, nil
+}
+func (fs memFS) Lstat(name string) (os.FileInfo, error) {
+	return fs.Stat(name)
+}
+func (fs memFS) Open(name string) (io.ReadCloser, error) {
+	data, err := fs.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+func (fs memFS) ReadFile(filename string) ([]byte, error) {
+	f, ok := fs[filename]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	if f.dir {
+		return nil, &os.PathError{Op: "read", Path: filename, Err: errors.New("is a directory")}
+	}
+	return f.data, nil
+}
+func (fs memFS) Remove(name string) error {
+	if _, ok := fs[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	for p := range fs {
+		if filepath.Dir(p) == name && p != name {
+			return &os.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+		}
+	}
+	delete(fs, name)
+	return nil
+}
+func (fs memFS) RemoveAll(path string) error {
+	for p := range fs {
+		if p == path || strings.HasPrefix(p, path+string(filepath.Separator)) {
+			delete(fs, p)
+		}
+	}
+	return nil
+}
+
+// paths returns the sorted paths of fs relative to root, directories with a trailing slash.
+func (fs memFS) paths(root string) []string {
+	var paths []string
+	for p, f := range fs {
+		if p == root {
+			continue
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if f.dir {
+			rel += "/"
+		}
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestWalkFileSystem(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "code")
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{
+			name:  "project at the root",
+			files: []string{"package.json", "node_modules/left-pad/package.json", "src/index.js"},
+			want:  []string{"package.json", "src/", "src/index.js"},
+		},
+		{
+			name:  "nested projects",
+			files: []string{"a/package.json", "a/node_modules/x/", "b/c/package.json", "b/c/node_modules/y/"},
+			want:  []string{"a/", "a/package.json", "b/", "b/c/", "b/c/package.json"},
+		},
+		{
+			name:  "no project",
+			files: []string{"node_modules/z/", "README.md"},
+			want:  []string{"README.md", "node_modules/", "node_modules/z/"},
+		},
+	}
+	defer func(fs FileSystem) { fileSystem = fs }(fileSystem)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newMemFS(root, tt.files)
+			fileSystem = fs
+			w := &walker{tasks: []Task{testDeps()}, root: root, out: ioutil.Discard, maxDepth: -1}
+			if err := w.walk(root, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if got := fs.paths(root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walk() left %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunnersFileSystem(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "code")
+	tests := []struct {
+		name      string
+		runner    string
+		files     map[string]string // contents by name, names ending with a slash are directories
+		configure func(w *walker)
+		want      []string
+	}{
+		{
+			name:   "web extension",
+			runner: "webext",
+			files: map[string]string{
+				"ext/manifest.json":                 `{"manifest_version": 3, "name": "ext", "version": "1.0"}`,
+				"ext/web-ext-artifacts/ext-1.0.zip": "",
+				"ext/dist/bundle.js":                "",
+				"site/manifest.json":                `{"name": "site", "display": "standalone"}`,
+				"site/web-ext-artifacts/":           "",
+			},
+			want: []string{"ext/", "ext/dist/", "ext/dist/bundle.js", "ext/manifest.json", "site/", "site/manifest.json", "site/web-ext-artifacts/"},
+		},
+		{
+			name:   "web extension build output",
+			runner: "webext",
+			files: map[string]string{
+				"manifest.json":      `{"manifest_version": 2, "name": "ext", "version": "1.0"}`,
+				"dist/bundle.js":     "",
+				"build/ext.js":       "",
+				"src/background.js":  "",
+				"web-ext-artifacts/": "",
+			},
+			configure: func(w *walker) { options.cleanBuildOutput = true },
+			want:      []string{"manifest.json", "src/", "src/background.js"},
+		},
+		{
+			name:   "build output",
+			runner: "build-output",
+			files: map[string]string{
+				"vite.config.js":   "export default { build: { outDir: 'public/build' } }",
+				"public/build/app": "",
+				"dist/app":         "",
+			},
+			want: []string{"dist/", "dist/app", "public/", "vite.config.js"},
+		},
+		{
+			name:   "docker context",
+			runner: "docker-context",
+			files: map[string]string{
+				"Dockerfile":    "FROM scratch",
+				".purge-docker": "# generated\n.cache\n../outside\n",
+				".cache/layer":  "",
+			},
+			want: []string{".purge-docker", "Dockerfile"},
+		},
+		{
+			name:   "monorepo caches",
+			runner: "monorepo",
+			files:  map[string]string{"nx.json": "{}", ".nx/cache/run.json": "", ".turbo/cookie": "", "common/temp/x": ""},
+			want:   []string{"common/", "nx.json"},
+		},
+		{
+			name:      "below the minimum size",
+			runner:    "npm",
+			files:     map[string]string{"package.json": "{}", "node_modules/x/index.js": strings.Repeat("x", 1023)},
+			configure: func(w *walker) { w.minSize = 1024 },
+			want:      []string{"node_modules/", "node_modules/x/", "node_modules/x/index.js", "package.json"},
+		},
+		{
+			name:      "above the minimum size",
+			runner:    "npm",
+			files:     map[string]string{"package.json": "{}", "node_modules/x/index.js": strings.Repeat("x", 1025)},
+			configure: func(w *walker) { w.minSize = 1024 },
+			want:      []string{"package.json"},
+		},
+	}
+	defer func(fs FileSystem, out io.Writer) { fileSystem, stderr = fs, out }(fileSystem, stderr)
+	defer func(o runnerOptions) { options = o }(options)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newMemFS(root, nil)
+			for name, data := range tt.files {
+				fs.add(filepath.Join(root, filepath.FromSlash(name)), memFile{dir: strings.HasSuffix(name, "/"), data: []byte(data)})
+			}
+			fileSystem, stderr = fs, ioutil.Discard
+			options = runnerOptions{root: root, cargoCleanMode: "full"}
+			w := &walker{tasks: []Task{testRunner(t, tt.runner)}, root: root, out: ioutil.Discard, maxDepth: -1}
+			if tt.configure != nil {
+				tt.configure(w)
+			}
+			if err := w.walk(root, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if got := fs.paths(root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walk() left %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlobFS(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "code")
+	fs := newMemFS(root, []string{
+		"target/debug/incremental/a", "target/release/incremental/", "target/doc/",
+		"target/x86_64-unknown-linux-musl/release/incremental/", "fast.pyx", "fast.c", "fast.cpython-38.so",
+	})
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"fast.c", []string{"fast.c"}},
+		{"missing.c", nil},
+		{"*.pyx", []string{"fast.pyx"}},
+		{"fast.*.so", []string{"fast.cpython-38.so"}},
+		{"target/*/incremental", []string{"target/debug/incremental", "target/release/incremental"}},
+		{"target/*/*/incremental", []string{"target/x86_64-unknown-linux-musl/release/incremental"}},
+		{"missing/*", nil},
+	}
+	defer func(fs FileSystem) { fileSystem = fs }(fileSystem)
+	fileSystem = fs
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			matches, err := globFS(filepath.Join(root, filepath.FromSlash(tt.pattern)))
+			if err != nil {
+				t.Fatalf("globFS() = %v", err)
+			}
+			var got []string
+			for _, m := range matches {
+				rel, _ := filepath.Rel(root, m)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("globFS(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+	if _, err := globFS(filepath.Join(root, "[")); err != filepath.ErrBadPattern {
+		t.Errorf("globFS(\"[\") = %v, want %v", err, filepath.ErrBadPattern)
+	}
+}
+
+func TestWalkFS(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "code")
+	fs := newMemFS(root, []string{"a/b/file", "a/skip/file", "c/", "file"})
+	defer func(fs FileSystem) { fileSystem = fs }(fileSystem)
+	fileSystem = fs
+	var visited []string
+	err := walkFS(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Name() == "skip" {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(root, path)
+		visited = append(visited, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkFS() = %v", err)
+	}
+	if want := []string{".", "a", "a/b", "a/b/file", "c", "file"}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("walkFS() visited %q, want %q", visited, want)
+	}
+	missing := filepath.Join(root, "missing")
+	if err := walkFS(missing, func(path string, info os.FileInfo, err error) error { return err }); !os.IsNotExist(err) {
+		t.Errorf("walkFS(%s) = %v, want %v", missing, err, os.ErrNotExist)
+	}
+}
+
+// lockedFS is a memFS which fails to remove anything, like a read-only directory or files locked by another process.
+type lockedFS struct {
+	memFS
+}
+
+func (fs lockedFS) RemoveAll(path string) error {
+	return &os.PathError{Op: "unlinkat", Path: path, Err: os.ErrPermission}
+}
+
+func TestWalkRemoveError(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "code")
+	tests := []struct {
+		runner string
+		files  []string
+	}{
+		{"composer", []string{"composer.json", "composer.lock", "vendor/autoload.php"}},
+		{"npm", []string{"package.json", "node_modules/left-pad/package.json"}},
+	}
+	defer func(fs FileSystem) { fileSystem = fs }(fileSystem)
+	for _, tt := range tests {
+		t.Run(tt.runner, func(t *testing.T) {
+			fileSystem = lockedFS{newMemFS(root, tt.files)}
+			w := &walker{tasks: []Task{testRunner(t, tt.runner)}, root: root, out: ioutil.Discard, maxDepth: -1}
+			err := w.walk(root, 0)
+			if !errors.Is(err, os.ErrPermission) {
+				t.Fatalf("walk() = %v, want %v", err, os.ErrPermission)
+			}
+			var taskErr *TaskError
+			if !errors.As(err, &taskErr) || taskErr.Tool != tt.runner || taskErr.Path != root {
+				t.Errorf("walk() = %#v, want a TaskError of %s in %s", err, tt.runner, root)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package purge
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// freeSpace is not supported on this platform.
+func freeSpace(path string) (uint64, error) {
+	return 0, fmt.Errorf("failed to query free space of %s: not supported on %s", path, runtime.GOOS)
+}
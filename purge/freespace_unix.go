@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package purge
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// freeSpace returns the number of bytes available to unprivileged users on the filesystem containing path.
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to query free space of %s: %w", path, err)
+	}
+	// the field types differ between platforms
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,137 @@
+package purge
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitRoot returns the root directory of the git repository containing path,
+// or an empty string if path isn't part of a repository.
+func gitRoot(path string) string {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		// `.git` is a file instead of a directory within worktrees and submodules
+		if _, err := fileSystem.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if dir == filepath.Dir(dir) {
+			return ""
+		}
+	}
+}
+
+// lastCommit returns the committer date of the most recent commit in the given repository.
+func lastCommit(root string) (time.Time, error) {
+	cmd := exec.Command(appName("git"), "log", "-1", "--format=%ct")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit date %q of repository %s: %w", out, root, err)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// lastActivity returns the date of the last commit of the repository containing dir.
+// It falls back to the given modification time outside of repositories
+// or if the date can't be queried (e.g. no commits yet or git isn't installed).
+func (w *walker) lastActivity(dir string, modTime time.Time) time.Time {
+	root := gitRoot(dir)
+	if root == "" {
+		return modTime
+	}
+	if date, ok := w.commits[root]; ok {
+		return date
+	}
+	date, err := lastCommit(root)
+	if err != nil {
+		date = modTime
+	}
+	if w.commits == nil {
+		w.commits = map[string]time.Time{}
+	}
+	w.commits[root] = date
+	return date
+}
+
+// submodulePaths returns the absolute paths of all submodules declared in the .gitmodules file in dir.
+func submodulePaths(dir string) ([]string, error) {
+	f, err := fileSystem.Open(filepath.Join(dir, ".gitmodules"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read submodules of %s: %w", dir, err)
+	}
+	defer f.Close()
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// e.g. `	path = vendor/lib`
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) != "path" {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, filepath.FromSlash(strings.TrimSpace(kv[1]))))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read submodules of %s: %w", dir, err)
+	}
+	return paths, nil
+}
+
+// markSubmodules remembers the submodules of the repository in dir, if its entries contain a .gitmodules file.
+func (w *walker) markSubmodules(dir string, entries []os.FileInfo) {
+	for _, entry := range entries {
+		if entry.Name() != ".gitmodules" || entry.IsDir() {
+			continue
+		}
+		paths, err := submodulePaths(dir)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return
+		}
+		if w.submodules == nil {
+			w.submodules = map[string]bool{}
+		}
+		for _, path := range paths {
+			w.submodules[path] = true
+		}
+		return
+	}
+}
+
+// isGitTracked reports whether git tracks any file below path.
+// Committed dependency directories are vendored on purpose, so they must not be removed.
+func isGitTracked(path string) bool {
+	if gitRoot(path) == "" {
+		return false
+	}
+	cmd := exec.Command(appName("git"), "ls-files", "--error-unmatch", "--", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	// exits with an error for untracked paths
+	return cmd.Run() == nil
+}
+
+// trackedArtifact returns the first existing artifact of task in dir which is tracked by git, if any.
+func trackedArtifact(dir string, task Task) string {
+	l, ok := task.(artifactLister)
+	if !ok {
+		return ""
+	}
+	for _, artifact := range l.Artifacts() {
+		path := filepath.Join(dir, artifact)
+		if _, err := fileSystem.Stat(path); err != nil || keepsArtifact(task, path) {
+			continue
+		}
+		if isGitTracked(path) {
+			return path
+		}
+	}
+	return ""
+}
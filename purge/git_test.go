@@ -0,0 +1,149 @@
+package purge
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGit runs git with the arguments in dir, commits are dated at the given time.
+func testGit(t *testing.T, dir string, date time.Time, args ...string) {
+	t.Helper()
+	if _, err := exec.LookPath(appName("git")); err != nil {
+		t.Skip("git is not installed")
+	}
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+	cmd := exec.Command(appName("git"), args...)
+	cmd.Dir = dir
+	stamp := fmt.Sprintf("%d +0000", date.Unix())
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+stamp, "GIT_COMMITTER_DATE="+stamp)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestGitIdle(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		name    string
+		commit  time.Duration // age of the last commit, no commits if 0
+		removed bool
+	}{
+		{"idle repository", 60 * day, true},
+		{"active repository", day, false},
+		// without commits the fresh modification time of the manifest counts
+		{"no commits", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"app/package.json", "app/node_modules/x/"})
+			defer cleanup()
+			testGit(t, dir, time.Now(), "init", "-q")
+			if tt.commit > 0 {
+				testGit(t, dir, time.Now().Add(-tt.commit), "add", "app/package.json")
+				testGit(t, dir, time.Now().Add(-tt.commit), "commit", "-q", "-m", "init")
+			}
+			w := &walker{tasks: []Task{testDeps()}, root: dir, out: ioutil.Discard, maxDepth: -1, gitIdle: 30 * day}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			_, err := os.Stat(filepath.Join(dir, "app", "node_modules"))
+			if removed := os.IsNotExist(err); removed != tt.removed {
+				t.Errorf("node_modules removed = %v, want %v", removed, tt.removed)
+			}
+		})
+	}
+}
+
+func TestSkipSubmodules(t *testing.T) {
+	gitmodules := "[submodule \"ui\"]\n\tpath = libs/ui\n\turl = https://example.com/ui.git\n" +
+		"[submodule \"theme\"]\n\tpath=libs/theme\n\turl = https://example.com/theme.git\n"
+	files := []string{
+		".gitmodules",
+		"package.json", "node_modules/x/",
+		"libs/ui/package.json", "libs/ui/node_modules/x/",
+		"libs/theme/package.json", "libs/theme/node_modules/x/",
+		"libs/own/package.json", "libs/own/node_modules/x/",
+	}
+	tests := []struct {
+		name     string
+		skipSubs bool
+		kept     []string
+	}{
+		{"include submodules", false, nil},
+		{"skip submodules", true, []string{"libs/theme/node_modules", "libs/ui/node_modules"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			if err := ioutil.WriteFile(filepath.Join(dir, ".gitmodules"), []byte(gitmodules), 0644); err != nil {
+				t.Fatal(err)
+			}
+			w := &walker{tasks: []Task{testDeps()}, root: dir, out: ioutil.Discard, maxDepth: -1, skipSubs: tt.skipSubs}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			var kept []string
+			for _, name := range []string{"libs/own/node_modules", "libs/theme/node_modules", "libs/ui/node_modules", "node_modules"} {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+					kept = append(kept, name)
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("walk() kept %q, want %q", kept, tt.kept)
+			}
+		})
+	}
+}
+
+func TestKeepTracked(t *testing.T) {
+	tests := []struct {
+		name        string
+		keepTracked bool
+		kept        []string
+	}{
+		{"remove all", false, nil},
+		{"keep tracked", true, []string{"vendored/node_modules"}},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{
+				"vendored/package.json", "vendored/node_modules/x/index.js",
+				"ignored/package.json", "ignored/node_modules/x/index.js",
+			})
+			defer cleanup()
+			testGit(t, dir, time.Now(), "init", "-q")
+			testGit(t, dir, time.Now(), "add", "vendored", "ignored/package.json")
+			testGit(t, dir, time.Now(), "commit", "-q", "-m", "init")
+			var out bytes.Buffer
+			stderr = &out
+			w := &walker{tasks: []Task{testDeps()}, root: dir, out: ioutil.Discard, maxDepth: -1, keepTracked: tt.keepTracked}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			var kept []string
+			for _, name := range []string{"ignored/node_modules", "vendored/node_modules"} {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+					kept = append(kept, name)
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("walk() kept %q, want %q", kept, tt.kept)
+			}
+			// the committed directory is reported
+			if warned := strings.Contains(out.String(), "committed to git"); warned != tt.keepTracked {
+				t.Errorf("walk() printed %q", out.String())
+			}
+		})
+	}
+}
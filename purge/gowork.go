@@ -0,0 +1,112 @@
+package purge
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// goWorkModules returns the module directories listed in the use directives of the go.work file at path.
+func goWorkModules(path string) ([]string, error) {
+	f, err := fileSystem.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go workspace %s: %w", path, err)
+	}
+	defer f.Close()
+	var dirs []string
+	inBlock := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock:
+			dirs = append(dirs, strings.Trim(fields[0], `"`))
+		case fields[0] == "use" && len(fields) > 1 && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "use" && len(fields) > 1:
+			dirs = append(dirs, strings.Trim(fields[1], `"`))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read go workspace %s: %w", path, err)
+	}
+	return dirs, nil
+}
+
+// removeGoWorkVendor removes the vendor directory of the go workspace of the go.work file at path
+// and of each of its modules. Modules outside of the workspace directory are left alone.
+func removeGoWorkVendor(path string) error {
+	modules, err := goWorkModules(path)
+	if err != nil {
+		return err
+	}
+	root := filepath.Dir(path)
+	dirs := []string{filepath.Join(root, "vendor")}
+	for _, module := range modules {
+		rel := filepath.Clean(filepath.FromSlash(module))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			fmt.Fprintf(stderr, "skipping module %q of %s: not inside the workspace directory\n", module, path)
+			continue
+		}
+		if rel != "." {
+			dirs = append(dirs, filepath.Join(root, rel, "vendor"))
+		}
+	}
+	for _, dir := range dirs {
+		if keepsGoVendor(dir) {
+			logf("skipping %s: committed to git", dir)
+			continue
+		}
+		if err := removeAll(dir); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// keepsGoVendor reports whether the vendor directory dir is kept, as git tracks it.
+// Some projects commit their vendored dependencies on purpose, e.g. for hermetic builds - unlike a node_modules
+// directory, a vendor directory of go is not restored by a download.
+func keepsGoVendor(dir string) bool {
+	return filepath.Base(dir) == "vendor" && isGitTracked(dir)
+}
+
+// removeGoVendor removes the vendor directory of the go module or workspace of the go.mod or go.work file at path
+// unless git tracks it, and - with bin set - the bin directory of the project, which usually holds locally built tools.
+func removeGoVendor(path string, bin bool) error {
+	dir := filepath.Dir(path)
+	// a workspace vendors the dependencies of all its modules, even if it is a module itself
+	if work := filepath.Join(dir, "go.work"); path != work {
+		if _, err := fileSystem.Stat(work); err == nil {
+			path = work
+		}
+	}
+	if filepath.Base(path) == "go.work" {
+		if err := removeGoWorkVendor(path); err != nil {
+			return err
+		}
+	}
+	names := []string{"vendor"}
+	if bin {
+		names = append(names, "bin")
+	}
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		if keepsGoVendor(p) {
+			logf("skipping %s: committed to git", p)
+			continue
+		}
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
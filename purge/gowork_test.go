@@ -0,0 +1,124 @@
+package purge
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGoWorkModules(t *testing.T) {
+	tests := []struct {
+		name string
+		work string
+		want []string
+	}{
+		{"single", "go 1.18\n\nuse ./api\n", []string{"./api"}},
+		{"block", "go 1.18\n\nuse (\n\t.\n\t./api // the server\n\t\"./tools/gen\"\n)\n", []string{".", "./api", "./tools/gen"}},
+		{"replace only", "go 1.18\n\nreplace example.com/a => ./a\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, nil)
+			defer cleanup()
+			path := filepath.Join(dir, "go.work")
+			if err := ioutil.WriteFile(path, []byte(tt.work), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := goWorkModules(path)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("goWorkModules() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveGoVendor(t *testing.T) {
+	work := "go 1.18\n\nuse (\n\t.\n\t./api\n\t./tools/gen\n\t../shared\n)\n"
+	files := []string{
+		"ws/go.work", "ws/go.mod", "ws/vendor/", "ws/bin/",
+		"ws/api/go.mod", "ws/api/vendor/",
+		"ws/tools/gen/go.mod", "ws/tools/gen/vendor/",
+		"ws/unused/go.mod", "ws/unused/vendor/",
+		"shared/go.mod", "shared/vendor/",
+	}
+	tests := []struct {
+		name     string
+		manifest string
+		bin      bool
+		kept     []string
+	}{
+		{"workspace", "ws/go.work", false, []string{"ws/bin/", "ws/unused/vendor/", "shared/vendor/"}},
+		{"module of a workspace", "ws/go.mod", false, []string{"ws/bin/", "ws/unused/vendor/", "shared/vendor/"}},
+		{"with bin", "ws/go.work", true, []string{"ws/unused/vendor/", "shared/vendor/"}},
+		{"module", "ws/unused/go.mod", false, []string{"ws/vendor/", "ws/bin/", "ws/api/vendor/", "ws/tools/gen/vendor/", "shared/vendor/"}},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	// modules outside of the workspace are reported
+	stderr = ioutil.Discard
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			if err := ioutil.WriteFile(filepath.Join(dir, "ws", "go.work"), []byte(work), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := removeGoVendor(filepath.Join(dir, filepath.FromSlash(tt.manifest)), tt.bin); err != nil {
+				t.Fatalf("removeGoVendor() = %v", err)
+			}
+			var kept []string
+			for _, name := range files {
+				if name[len(name)-1] != '/' {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+					kept = append(kept, name)
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("removeGoVendor() kept %q, want %q", kept, tt.kept)
+			}
+		})
+	}
+}
+
+func TestRemoveGoVendorTracked(t *testing.T) {
+	tests := []struct {
+		name    string
+		tracked []string // committed files
+		kept    []string
+	}{
+		{"untracked", nil, nil},
+		{"tracked module vendor", []string{"vendor/modules.txt"}, []string{"vendor/"}},
+		{"tracked workspace module vendor", []string{"api/vendor/modules.txt"}, []string{"api/vendor/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"go.mod", "vendor/modules.txt", "api/go.mod", "api/vendor/modules.txt"})
+			defer cleanup()
+			if err := ioutil.WriteFile(filepath.Join(dir, "go.work"), []byte("go 1.18\n\nuse (\n\t.\n\t./api\n)\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			testGit(t, dir, time.Now(), "init", "-q")
+			if len(tt.tracked) > 0 {
+				testGit(t, dir, time.Now(), append([]string{"add", "--"}, tt.tracked...)...)
+				testGit(t, dir, time.Now(), "commit", "-q", "-m", "vendor")
+			}
+			if err := removeGoVendor(filepath.Join(dir, "go.work"), false); err != nil {
+				t.Fatalf("removeGoVendor() = %v", err)
+			}
+			var kept []string
+			for _, name := range []string{"api/vendor/", "vendor/"} {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+					kept = append(kept, name)
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("removeGoVendor() kept %q, want %q", kept, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// gradleWrapper returns the path of the project's wrapper script in dir - or an empty string, if there is none.
+func gradleWrapper(dir string) string {
+	wrapper := "gradlew"
+	if runtime.GOOS == "windows" {
+		wrapper = "gradlew.bat"
+	}
+	if _, err := fileSystem.Stat(filepath.Join(dir, wrapper)); err != nil {
+		return ""
+	}
+	return filepath.Join(dir, wrapper)
+}
+
+// canRunGradle reports whether the gradle project of the build script at path has a wrapper script
+// or a global gradle installation to fall back on. The runner is available with a java runtime alone,
+// which only suffices for projects with a wrapper.
+func canRunGradle(path string) bool {
+	if gradleWrapper(filepath.Dir(path)) != "" {
+		return true
+	}
+	if _, err := exec.LookPath("gradle"); err == nil {
+		return true
+	}
+	logf("skipping %s: no gradle wrapper and gradle was not found in PATH", path)
+	return false
+}
+
+// gradleCommand returns the command to run the given gradle task in dir,
+// preferring the project's wrapper script over a global gradle installation.
+func gradleCommand(ctx context.Context, dir, task string) *exec.Cmd {
+	if wrapper := gradleWrapper(dir); wrapper != "" {
+		return exec.CommandContext(ctx, wrapper, task)
+	}
+	return exec.CommandContext(ctx, "gradle", task)
+}
+
+// cleanGradle runs `gradle clean` for the gradle project of the build script at path
+// and removes the project's build and configuration caches, which `gradle clean` leaves alone.
+// With deep set, the whole project-local .gradle directory is removed.
+func cleanGradle(path string, deep bool) error {
+	dir := filepath.Dir(path)
+	ctx, cancel := commandContext()
+	defer cancel()
+	cmd := gradleCommand(ctx, dir, "clean")
+	cmd.Dir = dir
+	cmd.Env = commandEnv
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w\n%s", cmd.String(), commandError(ctx, err), string(out))
+	}
+	caches := []string{
+		filepath.Join(".gradle", "caches", "build-cache-1"),
+		filepath.Join(".gradle", "configuration-cache"),
+	}
+	if deep {
+		caches = []string{".gradle"}
+	}
+	for _, name := range caches {
+		p := filepath.Join(dir, name)
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// cleanMaven runs `mvn clean` for the maven project of the pom.xml at path.
+func cleanMaven(path string) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "mvn", "--batch-mode", "clean")
+	cmd.Dir = filepath.Dir(path)
+	cmd.Env = commandEnv
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w\n%s", cmd.String(), commandError(ctx, err), string(out))
+	}
+	return nil
+}
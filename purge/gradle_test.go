@@ -0,0 +1,76 @@
+package purge
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCleanGradle(t *testing.T) {
+	files := []string{
+		"build.gradle.kts",
+		".gradle/8.4/fileHashes/fileHashes.bin",
+		".gradle/caches/build-cache-1/0a1b2c",
+		".gradle/caches/transforms-3/metadata.bin",
+		".gradle/configuration-cache/d4e5f6/entry.bin",
+		"src/main/kotlin/App.kt",
+	}
+	tests := []struct {
+		name string
+		deep bool
+		want []string
+	}{
+		{"caches", false, []string{
+			".gradle/",
+			".gradle/8.4/",
+			".gradle/8.4/fileHashes/",
+			".gradle/8.4/fileHashes/fileHashes.bin",
+			".gradle/caches/",
+			".gradle/caches/transforms-3/",
+			".gradle/caches/transforms-3/metadata.bin",
+			"build.gradle.kts",
+			"src/",
+			"src/main/",
+			"src/main/kotlin/",
+			"src/main/kotlin/App.kt",
+		}},
+		{"deep", true, []string{
+			"build.gradle.kts",
+			"src/",
+			"src/main/",
+			"src/main/kotlin/",
+			"src/main/kotlin/App.kt",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{"gradle": ""})
+			defer restore()
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			if err := cleanGradle(filepath.Join(dir, "build.gradle.kts"), tt.deep); err != nil {
+				t.Fatalf("cleanGradle() = %v", err)
+			}
+			if got, want := calls(), []string{"gradle clean"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("cleanGradle() ran %q, want %q", got, want)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cleanGradle() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanGradleFailed(t *testing.T) {
+	_, restore := testTools(t, map[string]string{"gradle": "exit 1"})
+	defer restore()
+	dir, cleanup := testTree(t, []string{"build.gradle", ".gradle/configuration-cache/entry.bin"})
+	defer cleanup()
+	if err := cleanGradle(filepath.Join(dir, "build.gradle"), false); err == nil {
+		t.Fatal("cleanGradle() = nil, want error")
+	}
+	want := []string{".gradle/", ".gradle/configuration-cache/", ".gradle/configuration-cache/entry.bin", "build.gradle"}
+	if got := testFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("cleanGradle() kept %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package purge
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// cleanStack cleans the Haskell project of the stack.yaml file at path with `stack clean` and removes .stack-work afterwards.
+func cleanStack(path string) error {
+	return cleanHaskell(path, "stack", ".stack-work")
+}
+
+// cleanCabal cleans the Haskell package of the *.cabal file at path with `cabal clean` and removes dist-newstyle afterwards.
+func cleanCabal(path string) error {
+	return cleanHaskell(path, "cabal", "dist-newstyle")
+}
+
+// cleanHaskell runs `<tool> clean` in the directory of path and removes the build directory of the tool.
+// Like `dotnet clean`, the command fails for projects which can't be configured anymore,
+// e.g. for a missing compiler version, so a failure is reported, but doesn't stop the removal.
+func cleanHaskell(path, tool, build string) error {
+	dir := filepath.Dir(path)
+	ctx, cancel := commandContext()
+	defer cancel()
+	cmd := exec.CommandContext(ctx, appName(tool), "clean")
+	cmd.Dir = dir
+	cmd.Env = commandEnv
+	if out, err := cmd.CombinedOutput(); err != nil {
+		fmt.Fprintf(stderr, "failed to run command %q, removing %s instead: %v\n%s\n", cmd.String(), build, commandError(ctx, err), string(out))
+	}
+	p := filepath.Join(dir, build)
+	if err := removeAll(p); err != nil {
+		return fmt.Errorf("failed to remove path %s: %w", p, err)
+	}
+	return nil
+}
+
+// isCabalName reports whether the file is the package description of a cabal package.
+func isCabalName(name string) bool {
+	return strings.HasSuffix(name, ".cabal") && name != ".cabal"
+}
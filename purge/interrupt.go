@@ -0,0 +1,8 @@
+package purge
+
+import (
+	"errors"
+)
+
+// errInterrupted stops the walk after its context was canceled, e.g. by an interrupt of the user.
+var errInterrupted = errors.New("interrupted")
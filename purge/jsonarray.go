@@ -0,0 +1,71 @@
+package purge
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// jsonDocument collects the results of a whole run to write them as a single JSON document.
+// Unlike the line based output it has to buffer all matches, which needs memory proportional to their number.
+// Like the line based JSON output, it lists the project directories instead of the matched manifests.
+type jsonDocument struct {
+	Matches []jsonMatch `json:"matches"`
+	Summary jsonSummary `json:"summary"`
+	Errors  []jsonError `json:"errors"`
+}
+
+type jsonMatch struct {
+	Path string `json:"path"`
+}
+
+// jsonError is an error of the run, the failure of a runner names the tool and the project directory.
+type jsonError struct {
+	Tool  string `json:"tool,omitempty"`
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error"`
+}
+
+type jsonSummary struct {
+	Matches int  `json:"matches"`
+	Skipped int  `json:"skipped"`
+	Errors  int  `json:"errors"`
+	Dry     bool `json:"dry"`
+	// Interrupted marks the document of a run stopped by a signal, which lists the matches processed so far
+	Interrupted bool `json:"interrupted"`
+}
+
+func newJSONDocument() *jsonDocument {
+	// encode empty lists as [] instead of null
+	return &jsonDocument{Matches: []jsonMatch{}, Errors: []jsonError{}}
+}
+
+func (d *jsonDocument) add(path string) {
+	d.Matches = append(d.Matches, jsonMatch{Path: path})
+}
+
+// write completes the summary with the state of the walker and writes the document.
+func (d *jsonDocument) write(out io.Writer, w *walker, errs ...error) error {
+	for _, err := range append(w.failures, errs...) {
+		var taskErr *TaskError
+		if errors.As(err, &taskErr) {
+			d.Errors = append(d.Errors, jsonError{Tool: taskErr.Tool, Path: taskErr.Path, Error: taskErr.Err.Error()})
+			continue
+		}
+		d.Errors = append(d.Errors, jsonError{Error: err.Error()})
+	}
+	d.Summary = jsonSummary{
+		Matches: len(d.Matches),
+		Skipped: len(w.skipped),
+		Errors:  len(d.Errors),
+		Dry:     w.dry,
+	}
+	for _, err := range errs {
+		if errors.Is(err, errInterrupted) {
+			d.Summary.Interrupted = true
+		}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
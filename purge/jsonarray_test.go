@@ -0,0 +1,48 @@
+package purge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestJSONDocumentErrors(t *testing.T) {
+	w := &walker{failures: []error{
+		&TaskError{Tool: "npm", Path: "/code/app", Err: errors.New("permission denied")},
+		errors.New("failed to read directory /code/locked"),
+	}}
+	d := newJSONDocument()
+	d.add("/code/web")
+	var out bytes.Buffer
+	if err := d.write(&out, w, errInterrupted); err != nil {
+		t.Fatalf("write() = %v", err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("write() wrote an invalid document: %v\n%s", err, out.String())
+	}
+	var keys []string
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	if len(keys) != 3 || doc["matches"] == nil || doc["summary"] == nil || doc["errors"] == nil {
+		t.Errorf("write() wrote the keys %q, want matches, summary and errors", keys)
+	}
+	var errs []jsonError
+	if err := json.Unmarshal(doc["errors"], &errs); err != nil {
+		t.Fatal(err)
+	}
+	want := []jsonError{
+		{Tool: "npm", Path: "/code/app", Error: "permission denied"},
+		{Error: "failed to read directory /code/locked"},
+		{Error: errInterrupted.Error()},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("write() wrote the errors %+v, want %+v", errs, want)
+	}
+	if d.Summary.Errors != len(want) || !d.Summary.Interrupted {
+		t.Errorf("write() summarized %+v", d.Summary)
+	}
+}
@@ -0,0 +1,74 @@
+package purge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// jsonLine is a single processed directory of the line delimited JSON output.
+type jsonLine struct {
+	Path   string `json:"path"`
+	Action string `json:"action"` // removed or would-remove
+	Tool   string `json:"tool"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// emitJSON writes the processed project directory at path as a single line of JSON.
+// Unlike emit, it is called after the task ran, so the line includes the reclaimed size.
+func (w *walker) emitJSON(path, tool string, size int64) error {
+	if w.realpath {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+	}
+	action := "removed"
+	if w.dry {
+		action = "would-remove"
+	}
+	// Encode terminates each value with a newline, which makes the output valid for jq
+	var err error
+	w.status.clear(func() {
+		err = json.NewEncoder(w.out).Encode(jsonLine{Path: path, Action: action, Tool: tool, Bytes: size})
+	})
+	return err
+}
+
+// record prints the path of something removed besides the matched projects, e.g. a test report or a broken link.
+// The line delimited JSON output names kind as its tool, so all lines on stdout stay JSON.
+func (w *walker) record(path, kind string) error {
+	if !w.jsonLines {
+		w.emit(path)
+		return nil
+	}
+	size, err := dirSize(path)
+	if err != nil {
+		return err
+	}
+	if err := w.emitJSON(path, kind, size); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
+
+// jsonToolSummary is the result of a single runner in the closing summary of the line delimited JSON output.
+type jsonToolSummary struct {
+	Tool     string `json:"tool"`
+	Projects int    `json:"projects"`
+	Bytes    int64  `json:"bytes"`
+	Failed   int    `json:"failed"`
+}
+
+// writeJSONSummary writes the results of all runners as the last line of the line delimited JSON output.
+// The summary of an interrupted run is marked, it covers the projects processed so far only.
+func writeJSONSummary(out io.Writer, results usageByTool, interrupted bool) error {
+	tools := []jsonToolSummary{}
+	for _, t := range results.sorted() {
+		tools = append(tools, jsonToolSummary{Tool: t.name, Projects: t.dirs, Bytes: t.size, Failed: t.failed})
+	}
+	return json.NewEncoder(out).Encode(struct {
+		Summary     []jsonToolSummary `json:"summary"`
+		Interrupted bool              `json:"interrupted"`
+	}{tools, interrupted})
+}
@@ -0,0 +1,32 @@
+package purge
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteJSONSummary(t *testing.T) {
+	results := usageByTool{}
+	results.add("npm", 100)
+	tests := []struct {
+		name        string
+		results     usageByTool
+		interrupted bool
+		want        string
+	}{
+		{"empty", usageByTool{}, false, `{"summary":[],"interrupted":false}` + "\n"},
+		{"complete", results, false, `{"summary":[{"tool":"npm","projects":1,"bytes":100,"failed":0}],"interrupted":false}` + "\n"},
+		{"interrupted", results, true, `{"summary":[{"tool":"npm","projects":1,"bytes":100,"failed":0}],"interrupted":true}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writeJSONSummary(&out, tt.results, tt.interrupted); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("writeJSONSummary() = %s, want %s", out.String(), tt.want)
+			}
+		})
+	}
+}
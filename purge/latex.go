@@ -0,0 +1,138 @@
+package purge
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// reDocumentClass matches the document class declaration of a LaTeX main document
+var reDocumentClass = regexp.MustCompile(`(?m)^[^%\n]*\\documentclass`)
+
+// latexAuxExtensions are the extensions of auxiliary files produced by LaTeX and its packages.
+// Generic extensions like `.log` are only ever removed next to a document with the same base name.
+var latexAuxExtensions = []string{
+	".aux", ".log", ".out", ".toc", ".lof", ".lot", ".bbl", ".blg", ".bcf", ".run.xml",
+	".fls", ".fdb_latexmk", ".synctex.gz", ".nav", ".snm", ".vrb", ".idx", ".ilg", ".ind",
+}
+
+// isLatexName reports whether the file name belongs to a LaTeX source.
+func isLatexName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".tex")
+}
+
+// isLatexDocument reports whether the LaTeX source at path is a main document rather than an included part.
+func isLatexDocument(path string) bool {
+	f, err := fileSystem.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	// the preamble is at the top of the file
+	head, err := ioutil.ReadAll(io.LimitReader(f, 64*1024))
+	if err != nil {
+		return false
+	}
+	return reDocumentClass.Match(head)
+}
+
+// cleanLatex removes the auxiliary files of all LaTeX main documents in the directory of the document at path.
+// The walk runs a task once per directory, but a directory may hold several documents, e.g. a paper and its slides.
+func cleanLatex(path string) error {
+	dir := filepath.Dir(path)
+	docs, err := latexDocuments(dir)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := cleanLatexDocument(doc); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{"build", "out"} {
+		p := filepath.Join(dir, name)
+		if keepsLatexOutput(p) {
+			continue
+		}
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// latexDocuments returns the paths of all LaTeX main documents in the directory dir.
+func latexDocuments(dir string) ([]string, error) {
+	entries, err := fileSystem.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file entries of directory %q: %w", dir, err)
+	}
+	var docs []string
+	for _, entry := range entries {
+		doc := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() && isLatexName(entry.Name()) && isLatexDocument(doc) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// keepsLatexOutput reports whether the output directory dir next to the LaTeX documents is kept.
+// Output directories are common names for other things, too, so only those holding LaTeX output are removed.
+func keepsLatexOutput(dir string) bool {
+	docs, err := latexDocuments(filepath.Dir(dir))
+	if err != nil {
+		return true
+	}
+	var bases []string
+	for _, doc := range docs {
+		bases = append(bases, strings.TrimSuffix(filepath.Base(doc), filepath.Ext(doc)))
+	}
+	return !isLatexOutputDir(dir, bases)
+}
+
+// cleanLatexDocument removes the auxiliary files of the LaTeX document at path,
+// using `latexmk -C` if available and its known extensions otherwise.
+func cleanLatexDocument(path string) error {
+	dir := filepath.Dir(path)
+	if _, err := exec.LookPath("latexmk"); err == nil {
+		ctx, cancel := commandContext()
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "latexmk", "-C", filepath.Base(path))
+		cmd.Dir = dir
+		cmd.Env = commandEnv
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to run command %q: %w\n%s", cmd.String(), commandError(ctx, err), string(out))
+		}
+		return nil
+	}
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, ext := range latexAuxExtensions {
+		p := filepath.Join(dir, base+ext)
+		if err := fileSystem.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	p := filepath.Join(dir, "_minted-"+base)
+	if err := removeAll(p); err != nil {
+		return fmt.Errorf("failed to remove path %s: %w", p, err)
+	}
+	return nil
+}
+
+// isLatexOutputDir reports whether dir holds the output of one of the documents with the given base names,
+// i.e. their auxiliary files or the recorder file of any LaTeX run.
+func isLatexOutputDir(dir string, bases []string) bool {
+	for _, base := range bases {
+		if _, err := fileSystem.Stat(filepath.Join(dir, base+".aux")); err == nil {
+			return true
+		}
+	}
+	matches, err := globFS(filepath.Join(dir, "*.fls"))
+	return err == nil && len(matches) > 0
+}
@@ -0,0 +1,122 @@
+package purge
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCleanLatex(t *testing.T) {
+	// without latexmk the auxiliary files are removed by their extensions
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", "")
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		removed []string
+		kept    []string
+	}{
+		{
+			name: "all documents of a directory",
+			files: map[string]string{
+				"paper.tex":  "\\documentclass{article}\n",
+				"paper.aux":  "",
+				"slides.tex": "\\documentclass{beamer}\n",
+				"slides.aux": "",
+				"slides.log": "",
+				"part.tex":   "\\section{Part}\n",
+				"part.log":   "",
+			},
+			removed: []string{"paper.aux", "slides.aux", "slides.log"},
+			kept:    []string{"paper.tex", "slides.tex", "part.tex", "part.log"},
+		},
+		{
+			name: "output directories with latex output only",
+			files: map[string]string{
+				"paper.tex":      "\\documentclass{article}\n",
+				"out/paper.aux":  "",
+				"build/main.go":  "package main\n",
+				"build/data.csv": "",
+			},
+			removed: []string{"out"},
+			kept:    []string{"build/main.go"},
+		},
+		{
+			name: "output directory with a recorder file",
+			files: map[string]string{
+				"paper.tex":       "\\documentclass{article}\n",
+				"build/other.fls": "",
+			},
+			removed: []string{"build"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "purge-latex")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for name, contents := range tt.files {
+				p := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := cleanLatex(filepath.Join(dir, "paper.tex")); err != nil {
+				t.Fatalf("cleanLatex() = %v", err)
+			}
+			for _, name := range tt.removed {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+					t.Errorf("%s was not removed", name)
+				}
+			}
+			for _, name := range tt.kept {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+					t.Errorf("%s was removed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanLatexDocument(t *testing.T) {
+	files := []string{"paper.tex", "paper.aux", "paper.toc", "paper.log", "paper.pdf", "_minted-paper/", "notes.log"}
+	tests := []struct {
+		name    string
+		latexmk bool
+		calls   []string
+		kept    []string
+	}{
+		// latexmk decides on its own, the fake one removes nothing
+		{"latexmk", true, []string{"latexmk -C paper.tex"}, []string{"_minted-paper/", "notes.log", "paper.aux", "paper.log", "paper.pdf", "paper.tex", "paper.toc"}},
+		{"known extensions", false, nil, []string{"notes.log", "paper.pdf", "paper.tex"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tools := map[string]string{}
+			if tt.latexmk {
+				tools["latexmk"] = ""
+			}
+			calls, restore := testTools(t, tools)
+			defer restore()
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			if err := cleanLatexDocument(filepath.Join(dir, "paper.tex")); err != nil {
+				t.Fatalf("cleanLatexDocument() = %v", err)
+			}
+			if got := calls(); !reflect.DeepEqual(got, tt.calls) {
+				t.Errorf("cleanLatexDocument() ran %q, want %q", got, tt.calls)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.kept) {
+				t.Errorf("cleanLatexDocument() kept %q, want %q", got, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package purge
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// runnerInfo is the machine-readable description of a runner.
+type runnerInfo struct {
+	Name      string   `json:"name"`
+	Patterns  []string `json:"patterns"`
+	Artifacts []string `json:"artifacts"`
+	Command   string   `json:"command,omitempty"`
+	Available bool     `json:"available"`
+}
+
+// ListRunners writes a JSON array describing all runners in their order of precedence.
+func ListRunners(out io.Writer) error {
+	infos := make([]runnerInfo, 0, len(registry))
+	for _, r := range registry {
+		info := runnerInfo{
+			Name:      r.name,
+			Patterns:  r.patterns,
+			Artifacts: r.artifacts,
+			Command:   r.command,
+			Available: r.Available(),
+		}
+		if info.Artifacts == nil {
+			info.Artifacts = []string{}
+		}
+		infos = append(infos, info)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(infos)
+}
@@ -0,0 +1,72 @@
+package purge_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/denisbrodbeck/purge-npm/purge"
+)
+
+func TestListRunners(t *testing.T) {
+	var out bytes.Buffer
+	if err := purge.ListRunners(&out); err != nil {
+		t.Fatalf("ListRunners() = %v", err)
+	}
+	var runners []struct {
+		Name      string   `json:"name"`
+		Patterns  []string `json:"patterns"`
+		Artifacts []string `json:"artifacts"`
+		Command   string   `json:"command"`
+		Available *bool    `json:"available"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &runners); err != nil {
+		t.Fatalf("ListRunners() printed invalid JSON: %v\n%s", err, out.String())
+	}
+	byName := map[string]int{}
+	for i, r := range runners {
+		if _, ok := byName[r.Name]; ok {
+			t.Errorf("runner %s is listed twice", r.Name)
+		}
+		byName[r.Name] = i
+		if len(r.Patterns) == 0 || r.Artifacts == nil || r.Available == nil {
+			t.Errorf("runner %s is incomplete: %+v", r.Name, r)
+		}
+	}
+	tests := []struct {
+		name     string
+		pattern  string
+		artifact string
+		command  string
+	}{
+		{"npm", "package.json", "node_modules", ""},
+		{"composer", "composer.json", "vendor", ""},
+		{"cargo", "Cargo.toml", "target", "cargo clean"},
+		{"webext", "manifest.json", "web-ext-artifacts", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, ok := byName[tt.name]
+			if !ok {
+				t.Fatalf("runner %s is not listed", tt.name)
+			}
+			r := runners[i]
+			if !contains(r.Patterns, tt.pattern) || !contains(r.Artifacts, tt.artifact) || r.Command != tt.command {
+				t.Errorf("runner %s = %+v, want pattern %q, artifact %q and command %q", tt.name, r, tt.pattern, tt.artifact, tt.command)
+			}
+		})
+	}
+	// yarn and pnpm projects have a lock file next to their package.json, which npm claims otherwise
+	if byName["npm"] < byName["yarn"] || byName["npm"] < byName["pnpm"] {
+		t.Errorf("npm is listed before yarn or pnpm")
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package purge
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// cleanMix cleans the Elixir project of the mix.exs file at path with `mix clean`
+// and removes its build and dependency directories afterwards.
+// Projects whose dependencies are missing already can't always be loaded by mix,
+// so a failing `mix clean` is reported, but doesn't stop the removal.
+func cleanMix(path string) error {
+	dir := filepath.Dir(path)
+	ctx, cancel := commandContext()
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "mix", "clean")
+	cmd.Dir = dir
+	cmd.Env = commandEnv
+	if out, err := cmd.CombinedOutput(); err != nil {
+		fmt.Fprintf(stderr, "failed to run command %q, removing _build and deps only: %v\n%s\n", cmd.String(), commandError(ctx, err), string(out))
+	}
+	for _, name := range []string{"_build", "deps"} {
+		p := filepath.Join(dir, name)
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// hexHome returns the directory of hex, the package manager of Elixir, which may be relocated by `HEX_HOME`.
+func hexHome() (string, error) {
+	if dir := os.Getenv("HEX_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".hex"), nil
+}
+
+// clearCachesMix removes the package archives downloaded by hex.
+// Hex itself and its registry cache are left alone, so mix keeps working offline.
+func clearCachesMix() error {
+	home, err := hexHome()
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(home, "packages")
+	if err := removeAll(p); err != nil {
+		return fmt.Errorf("failed to remove path %s: %w", p, err)
+	}
+	return nil
+}
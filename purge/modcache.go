@@ -0,0 +1,76 @@
+package purge
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// goModCache returns the module cache directory of the installed go toolchain.
+func goModCache() (string, error) {
+	cmd := exec.Command(appName("go"), "env", "GOMODCACHE", "GOPATH")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	// keep the empty first line of older go versions
+	lines := strings.Split(strings.TrimRight(string(out), "\r\n"), "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) != "" {
+		return strings.TrimSpace(lines[0]), nil
+	}
+	// go versions before 1.15 don't know GOMODCACHE and print an empty line
+	if len(lines) > 1 {
+		if gopath := filepath.SplitList(strings.TrimSpace(lines[1])); len(gopath) > 0 && gopath[0] != "" {
+			return filepath.Join(gopath[0], "pkg", "mod"), nil
+		}
+	}
+	return "", fmt.Errorf("failed to find go module cache")
+}
+
+// cleanGoDownloadTmp removes leftover temporary and lock files of interrupted module downloads,
+// which may block further fetches of the affected modules.
+func (w *walker) cleanGoDownloadTmp(modcache string) error {
+	dir := filepath.Join(modcache, "cache", "download")
+	err := walkFS(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".tmp" && ext != ".lock" {
+			return nil
+		}
+		if err := w.record(path, "go-download-tmp"); err != nil {
+			return err
+		}
+		if w.dry {
+			return nil
+		}
+		// the module cache is read-only by default, removing a file needs write access to its directory
+		parent := filepath.Dir(path)
+		pinfo, err := fileSystem.Stat(parent)
+		if err != nil {
+			return err
+		}
+		if pinfo.Mode()&0200 == 0 {
+			if err := os.Chmod(parent, pinfo.Mode()|0200); err != nil {
+				return fmt.Errorf("failed to make directory %s writable: %w", parent, err)
+			}
+			defer os.Chmod(parent, pinfo.Mode())
+		}
+		if err := fileSystem.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clean module downloads in %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package purge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGoModCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"GOMODCACHE", `printf '/opt/modcache\n/home/gopher/go\n'`, "/opt/modcache"},
+		{"GOPATH before go 1.15", `printf '\n/home/gopher/go:/opt/go\n'`, filepath.Join("/home/gopher/go", "pkg", "mod")},
+		{"unknown", `printf '\n\n'`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{"go": tt.script})
+			defer restore()
+			got, err := goModCache()
+			if got != tt.want || (err != nil) != (tt.want == "") {
+				t.Errorf("goModCache() = %q, %v, want %q", got, err, tt.want)
+			}
+			if want := []string{"go env GOMODCACHE GOPATH"}; !reflect.DeepEqual(calls(), want) {
+				t.Errorf("goModCache() ran %q, want %q", calls(), want)
+			}
+		})
+	}
+}
+
+func TestCleanGoDownloadTmp(t *testing.T) {
+	files := []string{
+		"cache/download/golang.org/x/text/@v/list",
+		"cache/download/golang.org/x/text/@v/v0.3.0.info",
+		"cache/download/golang.org/x/text/@v/v0.3.0.mod",
+		"cache/download/golang.org/x/text/@v/v0.3.0.zip",
+		"cache/download/golang.org/x/text/@v/v0.3.0.lock",
+		"cache/download/golang.org/x/text/@v/v0.3.1.zip123456.tmp",
+		"cache/download/sumdb/sum.golang.org/lookup/golang.org/x/text@v0.3.0",
+		"golang.org/x/text@v0.3.0/go.mod",
+		"golang.org/x/text@v0.3.0/unused.tmp",
+	}
+	stale := []string{
+		"cache/download/golang.org/x/text/@v/v0.3.0.lock",
+		"cache/download/golang.org/x/text/@v/v0.3.1.zip123456.tmp",
+	}
+	tests := []struct {
+		name string
+		dry  bool
+	}{
+		{"remove", false},
+		{"dry run", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			// the module cache is read-only
+			versions := filepath.Join(dir, "cache", "download", "golang.org", "x", "text", "@v")
+			if err := os.Chmod(versions, 0555); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Chmod(versions, 0755)
+			var out bytes.Buffer
+			w := &walker{out: &out, dry: tt.dry}
+			if err := w.cleanGoDownloadTmp(dir); err != nil {
+				t.Fatalf("cleanGoDownloadTmp() = %v", err)
+			}
+			var printed, removed string
+			for _, name := range stale {
+				printed += filepath.Join(dir, filepath.FromSlash(name)) + "\n"
+			}
+			for _, name := range files {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+					removed += filepath.Join(dir, filepath.FromSlash(name)) + "\n"
+				}
+			}
+			if out.String() != printed {
+				t.Errorf("cleanGoDownloadTmp() printed %q, want %q", out.String(), printed)
+			}
+			want := printed
+			if tt.dry {
+				want = ""
+			}
+			if removed != want {
+				t.Errorf("cleanGoDownloadTmp() removed %q, want %q", removed, want)
+			}
+			if info, err := os.Stat(versions); err != nil || info.Mode().Perm() != 0555 {
+				t.Errorf("cleanGoDownloadTmp() changed the permissions of %s", versions)
+			}
+		})
+	}
+}
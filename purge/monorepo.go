@@ -0,0 +1,39 @@
+package purge
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// monorepoMarkers are the workspace files of Nx, Turborepo and Rush. Bazel workspaces have a runner of their own.
+var monorepoMarkers = []string{"nx.json", "turbo.json", "rush.json"}
+
+// monorepoCaches are the cache directories of all supported monorepo tools, relative to the workspace root.
+var monorepoCaches = []string{".nx", ".turbo", filepath.Join("common", "temp")}
+
+// isMonorepoMarker reports whether the file name belongs to a monorepo workspace.
+func isMonorepoMarker(name string) bool {
+	for _, marker := range monorepoMarkers {
+		if name == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// removeMonorepoCaches removes the caches of all monorepo tools in the workspace of the file at path.
+// Workspaces often stack several tools, so the caches of all of them are removed at once.
+func removeMonorepoCaches(path string) error {
+	for _, pattern := range monorepoCaches {
+		matches, err := globFS(filepath.Join(filepath.Dir(path), pattern))
+		if err != nil {
+			return fmt.Errorf("failed to match pattern %s: %w", pattern, err)
+		}
+		for _, p := range matches {
+			if err := removeAll(p); err != nil {
+				return fmt.Errorf("failed to remove path %s: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
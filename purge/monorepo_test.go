@@ -0,0 +1,63 @@
+package purge
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestRemoveMonorepoCaches(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{
+			name: "stacked tools",
+			files: []string{
+				"nx.json", "turbo.json", "rush.json",
+				".nx/cache/run.json", ".turbo/cookies/1.cookie",
+				"common/temp/pnpm-lock.yaml", "common/config/rush/pnpm-config.json",
+				"packages/app/src/index.ts",
+			},
+			want: []string{
+				"common/", "common/config/", "common/config/rush/", "common/config/rush/pnpm-config.json",
+				"nx.json", "packages/", "packages/app/", "packages/app/src/", "packages/app/src/index.ts",
+				"rush.json", "turbo.json",
+			},
+		},
+		{
+			name:  "caches of other tools",
+			files: []string{"turbo.json", ".nx/cache/run.json", ".turbo/cookies/1.cookie"},
+			want:  []string{"turbo.json"},
+		},
+		{
+			name:  "no caches",
+			files: []string{"nx.json", "packages/app/"},
+			want:  []string{"nx.json", "packages/", "packages/app/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			runs := 0
+			monorepo := testRunner(t, "monorepo")
+			run := monorepo.run
+			monorepo.run = func(path string) error {
+				runs++
+				return run(path)
+			}
+			w := &walker{tasks: []Task{monorepo}, root: dir, out: ioutil.Discard, maxDepth: -1}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if runs != 1 {
+				t.Errorf("walk() ran the monorepo runner %d times, want once", runs)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walk() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
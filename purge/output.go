@@ -0,0 +1,37 @@
+package purge
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// syncWriter serializes writes to the underlying writer.
+// A single call of fmt.Fprint* issues a single write, so lines written by concurrent callers never interleave.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+var (
+	// stdout receives the paths of all processed matches
+	stdout io.Writer = &syncWriter{w: os.Stdout}
+	// stderr receives errors, notes and summaries
+	stderr io.Writer = &syncWriter{w: os.Stderr}
+	// verbose receives the details logged by logf, it is nil unless -verbose is set
+	verbose io.Writer
+)
+
+// logf writes a line of details to verbose, if set.
+func logf(format string, args ...interface{}) {
+	if verbose != nil {
+		fmt.Fprintf(verbose, format+"\n", args...)
+	}
+}
@@ -0,0 +1,53 @@
+package purge
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// slowWriter writes byte by byte and yields in between, so unsynchronized writers interleave their lines.
+type slowWriter struct {
+	buf bytes.Buffer
+}
+
+func (s *slowWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.buf.WriteByte(b)
+		runtime.Gosched()
+	}
+	return len(p), nil
+}
+
+func TestSyncWriter(t *testing.T) {
+	const writers, lines = 32, 50
+	var out slowWriter
+	w := &syncWriter{w: &out}
+	var wg sync.WaitGroup
+	wg.Add(writers)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				fmt.Fprintf(w, "/home/luke/code/project-%02d/node_modules-%02d\n", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	s := bufio.NewScanner(&out.buf)
+	for s.Scan() {
+		var i, j int
+		if n, err := fmt.Sscanf(s.Text(), "/home/luke/code/project-%02d/node_modules-%02d", &i, &j); n != 2 || err != nil {
+			t.Fatalf("interleaved line %q", s.Text())
+		}
+		seen[s.Text()] = true
+	}
+	if len(seen) != writers*lines {
+		t.Errorf("got %d distinct lines, want %d", len(seen), writers*lines)
+	}
+}
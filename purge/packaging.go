@@ -0,0 +1,62 @@
+package purge
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// reFlatpakKeys are the keys every flatpak-builder manifest declares, either in YAML or JSON
+var reFlatpakKeys = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^\s*"?(app-id|id)"?\s*:`),
+	regexp.MustCompile(`(?m)^\s*"?runtime"?\s*:`),
+	regexp.MustCompile(`(?m)^\s*"?modules"?\s*:`),
+}
+
+// isFlatpakManifest reports whether the YAML or JSON file at path is a flatpak-builder manifest.
+func isFlatpakManifest(path string) bool {
+	f, err := fileSystem.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	// manifests list their top-level keys early, no need to read huge files completely
+	head, err := ioutil.ReadAll(io.LimitReader(f, 64*1024))
+	if err != nil {
+		return false
+	}
+	for _, re := range reFlatpakKeys {
+		if !re.Match(head) {
+			return false
+		}
+	}
+	return true
+}
+
+// isFlatpakManifestName reports whether the file name could belong to a flatpak-builder manifest.
+func isFlatpakManifestName(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml", ".json":
+		return true
+	}
+	return false
+}
+
+// removeSnapcraftBuild removes the build directories of the snapcraft project of the manifest at path.
+// The manifest lives either in the project directory or in its `snap` subdirectory.
+func removeSnapcraftBuild(path string) error {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == "snap" {
+		dir = filepath.Dir(dir)
+	}
+	for _, name := range []string{"parts", "prime", "stage", filepath.Join("snap", ".snapcraft")} {
+		p := filepath.Join(dir, name)
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package purge
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsFlatpakManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     bool
+	}{
+		{"yaml", "app-id: org.example.App\nruntime: org.freedesktop.Platform\nmodules:\n  - name: app\n", true},
+		{"legacy id", "id: org.example.App\nruntime: org.freedesktop.Platform\nmodules: []\n", true},
+		{"json", "{\n  \"app-id\": \"org.example.App\",\n  \"runtime\": \"org.gnome.Platform\",\n  \"modules\": []\n}\n", true},
+		{"ci config", "image: golang\njobs:\n  test:\n    runtime: go\n", false},
+		{"compose file", "version: '3'\nservices:\n  db:\n    image: postgres\n", false},
+		{"package.json", `{"name": "app", "modules": []}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, nil)
+			defer cleanup()
+			path := filepath.Join(dir, "manifest.yml")
+			if err := ioutil.WriteFile(path, []byte(tt.manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := isFlatpakManifest(path); got != tt.want {
+				t.Errorf("isFlatpakManifest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackagingBuilds(t *testing.T) {
+	tests := []struct {
+		name   string
+		runner string
+		files  []string
+		want   []string
+	}{
+		{
+			name:   "flatpak",
+			runner: "flatpak",
+			files:  []string{"org.example.App.yml", ".flatpak-builder/cache/", "build-dir/", "src/"},
+			want:   []string{"build-dir/", "org.example.App.yml", "src/"},
+		},
+		{
+			name:   "snapcraft",
+			runner: "snapcraft",
+			files:  []string{"snapcraft.yaml", "parts/app/", "prime/bin/", "stage/lib/", "src/parts/"},
+			want:   []string{"snapcraft.yaml", "src/", "src/parts/"},
+		},
+		{
+			name:   "snapcraft in snap directory",
+			runner: "snapcraft",
+			files:  []string{"snap/snapcraft.yaml", "snap/.snapcraft/state", "snap/gui/icon.png", "parts/", "prime/", "stage/"},
+			want:   []string{"snap/", "snap/gui/", "snap/gui/icon.png", "snap/snapcraft.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			if err := testRunner(t, tt.runner).run(filepath.Join(dir, filepath.FromSlash(tt.files[0]))); err != nil {
+				t.Fatalf("run() = %v", err)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("run() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
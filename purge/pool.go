@@ -0,0 +1,73 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// workerPool runs submitted jobs on a fixed number of goroutines.
+// The first failing job cancels all jobs which haven't started yet.
+type workerPool struct {
+	jobs   chan func() error
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	err    error // the first error, set before ctx is cancelled
+}
+
+// newWorkerPool starts a pool of n workers.
+func newWorkerPool(n int) *workerPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &workerPool{jobs: make(chan func() error), ctx: ctx, cancel: cancel}
+	p.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				if p.ctx.Err() != nil {
+					// drain the queue after a failure
+					continue
+				}
+				if err := job(); err != nil {
+					p.stop(err)
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// stop records err as the result of the pool and cancels all waiting jobs.
+// Errors of jobs which were already running at that point are printed only.
+func (p *workerPool) stop(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		if err != p.err {
+			fmt.Fprintln(stderr, err)
+		}
+		return
+	}
+	p.err = err
+	p.cancel()
+}
+
+// submit queues the job and blocks until a worker picks it up.
+// It returns the error of an earlier job, if any, so the caller stops producing work.
+func (p *workerPool) submit(job func() error) error {
+	select {
+	case <-p.ctx.Done():
+		return p.err
+	case p.jobs <- job:
+		return nil
+	}
+}
+
+// wait waits for all running jobs to finish and returns the first error of any job.
+func (p *workerPool) wait() error {
+	close(p.jobs)
+	p.wg.Wait()
+	return p.err
+}
@@ -0,0 +1,52 @@
+package purge
+
+import (
+	"fmt"
+)
+
+// dryTasks returns copies of the tasks which only match, but never change anything, e.g. to preview a run.
+func dryTasks(tasks []Task) []Task {
+	dry := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if r, ok := t.(runner); ok {
+			r.run = func(path string) error {
+				return nil
+			}
+			t = r
+		}
+		dry = append(dry, t)
+	}
+	return dry
+}
+
+// preview walks path with a dry copy of the walker and prints each match and the reclaimable space to stderr.
+func (w *walker) preview(path string) error {
+	p := &walker{
+		ctx:          w.ctx,
+		tasks:        dryTasks(w.tasks),
+		root:         path,
+		out:          stderr,
+		dry:          true,
+		realpath:     w.realpath,
+		skipSubs:     w.skipSubs,
+		keepTracked:  w.keepTracked,
+		gitIdle:      w.gitIdle,
+		since:        w.since,
+		minSize:      w.minSize,
+		limitPerTool: w.limitPerTool,
+		maxDepth:     w.maxDepth,
+		exclude:      w.exclude,
+		include:      w.include,
+		rootMarkers:  w.rootMarkers,
+		sizes:        true,
+	}
+	if err := p.walk(path, 0); err != nil {
+		return err
+	}
+	var projects int
+	for _, n := range p.processed {
+		projects += n
+	}
+	fmt.Fprintf(stderr, "would purge %d %s with %s\n", projects, plural(projects, "project", "projects"), formatBytes(p.measured))
+	return nil
+}
@@ -0,0 +1,31 @@
+package purge
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// pythonCacheDirs are the caches of the python interpreter and its tools, which are regenerated on the next run.
+var pythonCacheDirs = []string{"__pycache__", ".pytest_cache", ".mypy_cache"}
+
+// isPythonCacheDir reports whether the directory name is one of pythonCacheDirs.
+// The caches turn up in every directory which ever held a python script, so they are matched by name alone.
+func isPythonCacheDir(name string) bool {
+	for _, dir := range pythonCacheDirs {
+		if name == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// removePythonCaches removes all python caches next to the matched cache directory at path.
+func removePythonCaches(path string) error {
+	for _, name := range pythonCacheDirs {
+		dir := filepath.Join(filepath.Dir(path), name)
+		if err := removeAll(dir); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", dir, err)
+		}
+	}
+	return nil
+}
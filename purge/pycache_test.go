@@ -0,0 +1,48 @@
+package purge
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestWalkPythonCaches(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{
+			name:  "without a project",
+			files: []string{"scripts/tool.py", "scripts/__pycache__/tool.cpython-311.pyc"},
+			want:  []string{"scripts/", "scripts/tool.py"},
+		},
+		{
+			name: "all caches",
+			files: []string{
+				".pytest_cache/v/cache/nodeids",
+				"app/.mypy_cache/3.11/app.json", "app/.pytest_cache/README.md",
+				"app/src/__pycache__/main.cpython-311.pyc", "app/src/main.py",
+			},
+			want: []string{"app/", "app/src/", "app/src/main.py"},
+		},
+		{
+			name:  "files of the same name",
+			files: []string{"notes/__pycache__", "notes/.mypy_cache"},
+			want:  []string{"notes/", "notes/.mypy_cache", "notes/__pycache__"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			w := &walker{tasks: []Task{testRunner(t, "pycache")}, root: dir, out: ioutil.Discard, maxDepth: -1}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walk() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
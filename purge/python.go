@@ -0,0 +1,140 @@
+package purge
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// pyenvRoot returns pyenv's root directory, which may be overridden by `PYENV_ROOT`.
+func pyenvRoot() (string, error) {
+	if dir := os.Getenv("PYENV_ROOT"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".pyenv"), nil
+}
+
+// removePythonVenv removes the virtual environments and bytecode caches of the python project of the file at path.
+// With deep set, a pyenv virtualenv named like the project directory is deleted as well.
+func removePythonVenv(path string, deep bool) error {
+	project := filepath.Dir(path)
+	for _, name := range []string{".venv", "venv"} {
+		dir := filepath.Join(project, name)
+		if keepsPythonVenv(dir) {
+			continue
+		}
+		if err := removeAll(dir); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", dir, err)
+		}
+	}
+	if _, err := fileSystem.Stat(filepath.Join(project, "setup.py")); err == nil {
+		if err := removeSetuptoolsBuild(project); err != nil {
+			return err
+		}
+	}
+	if !deep {
+		return nil
+	}
+	if _, err := exec.LookPath("pyenv"); err != nil {
+		return nil
+	}
+	root, err := pyenvRoot()
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(filepath.Dir(path))
+	if _, err := os.Stat(filepath.Join(root, "versions", name)); err != nil {
+		return nil
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "pyenv", "virtualenv-delete", "-f", name)
+	cmd.Env = commandEnv
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w\n%s", cmd.String(), commandError(ctx, err), string(out))
+	}
+	return nil
+}
+
+// keepsPythonVenv reports whether the directory dir is kept, as it is no virtual environment.
+// `venv` and `.venv` are common names for source directories, too - only real virtual environments are removed.
+func keepsPythonVenv(dir string) bool {
+	_, err := fileSystem.Stat(filepath.Join(dir, "pyvenv.cfg"))
+	return err != nil
+}
+
+// keepsPythonArtifact reports whether the artifact directory dir of the python runner is kept.
+// The setuptools output is only removed next to a setup.py, see removeSetuptoolsBuild.
+func keepsPythonArtifact(dir string) bool {
+	switch filepath.Base(dir) {
+	case "build", ".eggs":
+		_, err := fileSystem.Stat(filepath.Join(filepath.Dir(dir), "setup.py"))
+		return err != nil
+	}
+	return keepsPythonVenv(dir)
+}
+
+// removeSetuptoolsBuild removes the build output of the setuptools project in dir.
+// Bytecode caches and Cython output are left to their own runners, which see nested directories during the walk.
+func removeSetuptoolsBuild(dir string) error {
+	for _, name := range []string{"build", ".eggs"} {
+		p := filepath.Join(dir, name)
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// isCythonPackage reports whether the Cython source at path belongs to a python package, that is
+// a setup.py or pyproject.toml is next to it or in one of its parent directories up to the root of the walk.
+// Stray sources outside of packages may sit next to hand-written C files of the same name.
+func isCythonPackage(path string) bool {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		for _, name := range []string{"setup.py", "pyproject.toml"} {
+			if _, err := fileSystem.Stat(filepath.Join(dir, name)); err == nil {
+				return true
+			}
+		}
+		if dir == options.root || dir == filepath.Dir(dir) {
+			return false
+		}
+		if rel, err := filepath.Rel(options.root, dir); options.root != "" && (err != nil || strings.HasPrefix(rel, "..")) {
+			return false
+		}
+	}
+}
+
+// removeCythonOutput removes the C sources and compiled extensions generated from all Cython sources
+// in the directory of the source at path, as the walk runs a task once per directory.
+// They share the base name of their source, hand-written extensions without a .pyx are never touched.
+func removeCythonOutput(path string) error {
+	sources, err := globFS(filepath.Join(filepath.Dir(path), "*.pyx"))
+	if err != nil {
+		return fmt.Errorf("failed to find Cython sources next to %s: %w", path, err)
+	}
+	var generated []string
+	for _, source := range sources {
+		base := strings.TrimSuffix(source, ".pyx")
+		generated = append(generated, base+".c", base+".cpp")
+		for _, pattern := range []string{base + ".so", base + ".*.so", base + ".pyd", base + ".*.pyd"} {
+			matches, err := globFS(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to find Cython output of %s: %w", source, err)
+			}
+			generated = append(generated, matches...)
+		}
+	}
+	for _, p := range generated {
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package purge
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRemoveCythonOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		removed []string
+		kept    []string
+	}{
+		{
+			name:    "generated sources and extensions",
+			files:   []string{"fast.pyx", "fast.c", "fast.cpython-38-x86_64-linux-gnu.so", "fast.pyd"},
+			removed: []string{"fast.c", "fast.cpython-38-x86_64-linux-gnu.so", "fast.pyd"},
+			kept:    []string{"fast.pyx"},
+		},
+		{
+			name:    "all sources of a directory",
+			files:   []string{"fast.pyx", "fast.c", "other.pyx", "other.so"},
+			removed: []string{"fast.c", "other.so"},
+			kept:    []string{"fast.pyx", "other.pyx"},
+		},
+		{
+			name:    "hand-written extensions",
+			files:   []string{"fast.pyx", "fast.cpp", "native.c", "native.so", "faster.c"},
+			removed: []string{"fast.cpp"},
+			kept:    []string{"fast.pyx", "native.c", "native.so", "faster.c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "purge-cython")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for _, name := range tt.files {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := removeCythonOutput(filepath.Join(dir, "fast.pyx")); err != nil {
+				t.Fatalf("removeCythonOutput() = %v", err)
+			}
+			for _, name := range tt.removed {
+				if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+					t.Errorf("%s was not removed", name)
+				}
+			}
+			for _, name := range tt.kept {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("%s was removed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestIsCythonPackage(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []string
+		source string
+		want   bool
+	}{
+		{"setup.py", []string{"setup.py", "fast.pyx"}, "fast.pyx", true},
+		{"pyproject.toml", []string{"pyproject.toml", "fast.pyx"}, "fast.pyx", true},
+		{"package below the project", []string{"pyproject.toml", "pkg/core/fast.pyx"}, "pkg/core/fast.pyx", true},
+		{"no project", []string{"src/fast.pyx", "src/fast.c"}, "src/fast.pyx", false},
+		{"project above the root", []string{"setup.py", "root/fast.pyx"}, "root/fast.pyx", false},
+	}
+	defer func(o runnerOptions) { options = o }(options)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			options.root = dir
+			if strings.HasPrefix(tt.source, "root/") {
+				options.root = filepath.Join(dir, "root")
+			}
+			if got := isCythonPackage(filepath.Join(dir, tt.source)); got != tt.want {
+				t.Errorf("isCythonPackage(%s) = %v, want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemovePythonVenv(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{".venv", []string{".python-version", ".venv/pyvenv.cfg", ".venv/bin/python", "src/"}, []string{".python-version", "src/"}},
+		{"both", []string{"requirements.txt", ".venv/pyvenv.cfg", "venv/pyvenv.cfg"}, []string{"requirements.txt"}},
+		{"source directory named .venv", []string{"pyproject.toml", ".venv/__init__.py"}, []string{".venv/", ".venv/__init__.py", "pyproject.toml"}},
+		{"venv", []string{"pyproject.toml", "venv/pyvenv.cfg"}, []string{"pyproject.toml"}},
+		{"source directory named venv", []string{"pyproject.toml", "venv/__init__.py"}, []string{"pyproject.toml", "venv/", "venv/__init__.py"}},
+		{"setuptools", []string{"setup.py", "build/lib/", ".eggs/", "app/__pycache__/"}, []string{"app/", "app/__pycache__/", "setup.py"}},
+		{"build without setuptools", []string{"pyproject.toml", "build/lib/"}, []string{"build/", "build/lib/", "pyproject.toml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			if err := removePythonVenv(filepath.Join(dir, tt.files[0]), false); err != nil {
+				t.Fatalf("removePythonVenv() = %v", err)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removePythonVenv() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemovePythonVenvDeep(t *testing.T) {
+	tests := []struct {
+		name      string
+		deep      bool
+		pyenvRoot string // PYENV_ROOT below the temporary directory
+		want      []string
+	}{
+		{"not deep", false, "custom", nil},
+		{"PYENV_ROOT", true, "custom", []string{"pyenv virtualenv-delete -f app"}},
+		{"home directory", true, "", []string{"pyenv virtualenv-delete -f app"}},
+		{"no virtualenv of the project", true, "empty", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{"pyenv": ""})
+			defer restore()
+			dir, cleanup := testTree(t, []string{"app/.python-version", "app/.venv/", "custom/versions/app/", "home/.pyenv/versions/app/", "empty/versions/other/"})
+			defer cleanup()
+			pyenvRoot := ""
+			if tt.pyenvRoot != "" {
+				pyenvRoot = filepath.Join(dir, tt.pyenvRoot)
+			}
+			defer testSetenv(t, "PYENV_ROOT", pyenvRoot)()
+			defer testSetenv(t, "HOME", filepath.Join(dir, "home"))()
+			defer testSetenv(t, "USERPROFILE", filepath.Join(dir, "home"))()
+			if err := removePythonVenv(filepath.Join(dir, "app", ".python-version"), tt.deep); err != nil {
+				t.Fatalf("removePythonVenv() = %v", err)
+			}
+			if got := calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removePythonVenv() ran %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package purge
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ioLimiter throttles the throughput of removals. It is nil by default, which means unlimited.
+var ioLimiter *tokenBucket
+
+// simulatedErrors makes a fraction of all removals fail for testing purposes. It is nil by default.
+var simulatedErrors *errorInjector
+
+// errorInjector fails a fraction of calls, deterministically for a given seed.
+type errorInjector struct {
+	mu   sync.Mutex
+	rate float64
+	rand *rand.Rand
+}
+
+func newErrorInjector(rate float64, seed int64) *errorInjector {
+	return &errorInjector{rate: rate, rand: rand.New(rand.NewSource(seed))}
+}
+
+// fail reports whether the current call should fail.
+func (e *errorInjector) fail() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rand.Float64() < e.rate
+}
+
+// removeAttempts and removeRetryDelay are the defaults for retrying failed removals.
+// Editors, indexers and virus scanners on Windows hold files open for a moment, which makes removals fail with "Access is denied".
+const (
+	removeAttempts   = 3
+	removeRetryDelay = 100 * time.Millisecond
+)
+
+// removeAll removes path and everything it contains like os.RemoveAll,
+// but file by file at the rate of ioLimiter, if set - and retries a failed removal with the default backoff.
+func removeAll(path string) error {
+	return removeAllRetry(path, removeAttempts, removeRetryDelay)
+}
+
+// removeAllRetry removes path like removeAll and tries again up to attempts times in total on failure,
+// waiting delay before the first retry and doubling it for each further one.
+// Simulated failures are never retried, so the same seed keeps failing the same removals.
+func removeAllRetry(path string, attempts int, delay time.Duration) error {
+	if simulatedErrors != nil && simulatedErrors.fail() {
+		return fmt.Errorf("simulated failure removing %s", path)
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			logf("failed to remove %s, retrying in %v: %v", path, delay, err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = removeOnce(path); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// removeOnce removes path and everything it contains like os.RemoveAll,
+// but file by file at the rate of ioLimiter, if set.
+func removeOnce(path string) error {
+	if ioLimiter == nil {
+		return fileSystem.RemoveAll(path)
+	}
+	info, err := fileSystem.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := fileSystem.ReadDir(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			// an entry removed in the meantime, e.g. by the tool itself, is no reason to keep the rest
+			if err := removeOnce(filepath.Join(path, entry.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	} else {
+		// the freed blocks are written back to the filesystem on removal, so count the size of each file
+		ioLimiter.wait(info.Size())
+	}
+	// the directory is empty at this point
+	return fileSystem.RemoveAll(path)
+}
+
+// tokenBucket limits a rate of bytes per second with bursts of up to a second.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+func newTokenBucket(rate int64) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// wait blocks until n bytes may pass.
+// Requests larger than a second's worth are let through once the bucket is full, and leave it in debt.
+func (b *tokenBucket) wait(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		now := b.now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		b.last = now
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+		if b.tokens >= float64(n) || b.tokens >= b.rate {
+			b.tokens -= float64(n)
+			return
+		}
+		missing := float64(n) - b.tokens
+		if missing > b.rate-b.tokens {
+			missing = b.rate - b.tokens
+		}
+		b.sleep(time.Duration(missing / b.rate * float64(time.Second)))
+	}
+}
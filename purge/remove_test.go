@@ -0,0 +1,239 @@
+package purge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyFS is a memFS whose directory listings include entries which fail on access with the mapped error,
+// e.g. files removed by someone else in the meantime.
+type flakyFS struct {
+	memFS
+	failing map[string]error
+}
+
+func (fs flakyFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	entries, err := fs.memFS.ReadDir(dirname)
+	for p := range fs.failing {
+		if filepath.Dir(p) == dirname {
+			entries = append(entries, memFileInfo{name: filepath.Base(p)})
+		}
+	}
+	return entries, err
+}
+func (fs flakyFS) Lstat(name string) (os.FileInfo, error) {
+	if err, ok := fs.failing[name]; ok {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return fs.memFS.Lstat(name)
+}
+
+// busyFS is a memFS which fails the first removals, like files held open by an editor or a virus scanner for a moment.
+type busyFS struct {
+	memFS
+	busy  int // number of removals which fail
+	calls int
+}
+
+func (fs *busyFS) RemoveAll(path string) error {
+	fs.calls++
+	if fs.calls <= fs.busy {
+		return &os.PathError{Op: "unlinkat", Path: path, Err: os.ErrPermission}
+	}
+	return fs.memFS.RemoveAll(path)
+}
+
+func TestRemoveAllRetry(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "project")
+	tests := []struct {
+		name    string
+		busy    int
+		calls   int
+		wantErr bool
+	}{
+		{"first attempt", 0, 1, false},
+		{"fails twice", 2, 3, false},
+		{"fails every attempt", 3, 3, true},
+	}
+	defer func(fs FileSystem, limiter *tokenBucket) { fileSystem, ioLimiter = fs, limiter }(fileSystem, ioLimiter)
+	ioLimiter = nil
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &busyFS{memFS: newMemFS(root, []string{"package.json", "node_modules/a/index.js"}), busy: tt.busy}
+			fileSystem = fs
+			err := removeAllRetry(filepath.Join(root, "node_modules"), 3, time.Millisecond)
+			if (err != nil) != tt.wantErr || err != nil && !errors.Is(err, os.ErrPermission) {
+				t.Fatalf("removeAllRetry() = %v, want error %v", err, tt.wantErr)
+			}
+			if fs.calls != tt.calls {
+				t.Errorf("removeAllRetry() tried %d times, want %d", fs.calls, tt.calls)
+			}
+			want := []string{"package.json"}
+			if tt.wantErr {
+				want = []string{"node_modules/", "node_modules/a/", "node_modules/a/index.js", "package.json"}
+			}
+			if got := fs.paths(root); !reflect.DeepEqual(got, want) {
+				t.Errorf("removeAllRetry() left %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRemoveOnce(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "project")
+	tests := []struct {
+		name    string
+		failing map[string]error
+		wantErr bool
+		want    []string
+	}{
+		{
+			name: "complete",
+		},
+		{
+			name:    "entry removed in the meantime",
+			failing: map[string]error{filepath.Join(root, "node_modules", "a", "gone"): os.ErrNotExist},
+		},
+		{
+			name:    "entry which can't be removed",
+			failing: map[string]error{filepath.Join(root, "node_modules", "a", "locked"): os.ErrPermission},
+			wantErr: true,
+			want:    []string{"node_modules/", "node_modules/a/", "node_modules/b/", "node_modules/b/index.js", "package.json"},
+		},
+	}
+	defer func(fs FileSystem, limiter *tokenBucket) { fileSystem, ioLimiter = fs, limiter }(fileSystem, ioLimiter)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem := newMemFS(root, []string{"package.json", "node_modules/a/index.js", "node_modules/b/index.js"})
+			// only the rate limited removal walks the entries one by one
+			fileSystem, ioLimiter = flakyFS{memFS: mem, failing: tt.failing}, newTokenBucket(1<<30)
+			err := removeOnce(filepath.Join(root, "node_modules"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("removeOnce() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, os.ErrPermission) {
+				t.Errorf("removeOnce() = %v, want %v", err, os.ErrPermission)
+			}
+			want := tt.want
+			if want == nil {
+				want = []string{"package.json"}
+			}
+			if got := mem.paths(root); !reflect.DeepEqual(got, want) {
+				t.Errorf("removeOnce() left %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// testBucket returns a token bucket with the rate, which runs on a fake clock advanced by its sleeps only,
+// and a function returning the time passed on that clock.
+func testBucket(rate int64) (*tokenBucket, func() time.Duration) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	b := newTokenBucket(rate)
+	b.last = clock
+	b.now = func() time.Time { return clock }
+	b.sleep = func(d time.Duration) { clock = clock.Add(d) }
+	return b, func() time.Duration { return clock.Sub(start) }
+}
+
+func TestTokenBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    int64
+		waits   []int64
+		elapsed time.Duration
+	}{
+		{"burst", 100, []int64{50, 50}, 0},
+		{"sustained", 100, []int64{100, 100, 100}, 2 * time.Second},
+		{"small files", 100, []int64{40, 40, 40, 40, 40}, time.Second},
+		{"larger than a second", 100, []int64{250, 10}, 1600 * time.Millisecond},
+		{"fast rate", 1 << 20, []int64{1 << 20, 1 << 19}, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, elapsed := testBucket(tt.rate)
+			for _, n := range tt.waits {
+				b.wait(n)
+			}
+			if got := elapsed(); got < tt.elapsed-time.Millisecond || got > tt.elapsed+time.Millisecond {
+				t.Errorf("wait() took %v, want %v", got, tt.elapsed)
+			}
+		})
+	}
+}
+
+func TestRemoveAllLimited(t *testing.T) {
+	dir, cleanup := testTree(t, []string{"node_modules/a/index.js", "node_modules/b/index.js", "node_modules/b/lib/util.js", "node_modules/.bin/"})
+	defer cleanup()
+	for _, name := range []string{"a/index.js", "b/index.js", "b/lib/util.js"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "node_modules", filepath.FromSlash(name)), make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(l *tokenBucket) { ioLimiter = l }(ioLimiter)
+	var elapsed func() time.Duration
+	ioLimiter, elapsed = testBucket(100)
+	if err := removeAll(filepath.Join(dir, "node_modules")); err != nil {
+		t.Fatalf("removeAll() = %v", err)
+	}
+	if got := testFiles(t, dir); got != nil {
+		t.Errorf("removeAll() kept %q", got)
+	}
+	// the first 100 bytes pass with the initial burst
+	if got := elapsed(); got != 2*time.Second {
+		t.Errorf("removeAll() took %v, want %v", got, 2*time.Second)
+	}
+}
+
+func TestErrorInjector(t *testing.T) {
+	tests := []struct {
+		rate float64
+		seed int64
+	}{
+		{0, 1},
+		{0.1, 1},
+		{0.5, 42},
+		{1, 7},
+	}
+	const calls = 10000
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.rate), func(t *testing.T) {
+			first, second := newErrorInjector(tt.rate, tt.seed), newErrorInjector(tt.rate, tt.seed)
+			failed := 0
+			for i := 0; i < calls; i++ {
+				fail := first.fail()
+				if fail != second.fail() {
+					t.Fatalf("call %d failed differently with the same seed", i)
+				}
+				if fail {
+					failed++
+				}
+			}
+			if got := float64(failed) / calls; math.Abs(got-tt.rate) > 0.02 {
+				t.Errorf("fail() failed %d of %d calls, want a rate of %v", failed, calls, tt.rate)
+			}
+		})
+	}
+}
+
+func TestRemoveAllSimulated(t *testing.T) {
+	defer func(e *errorInjector) { simulatedErrors = e }(simulatedErrors)
+	simulatedErrors = newErrorInjector(1, 1)
+	dir, cleanup := testTree(t, []string{"node_modules/x/index.js"})
+	defer cleanup()
+	if err := removeAll(filepath.Join(dir, "node_modules")); err == nil || !strings.Contains(err.Error(), "simulated failure") {
+		t.Fatalf("removeAll() = %v, want a simulated failure", err)
+	}
+	if got, want := testFiles(t, dir), []string{"node_modules/", "node_modules/x/", "node_modules/x/index.js"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("removeAll() kept %q, want %q", got, want)
+	}
+}
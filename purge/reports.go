@@ -0,0 +1,59 @@
+package purge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// reportDirs are test and coverage report directories within a project
+	reportDirs = []string{"coverage", ".nyc_output"}
+	// reportFiles are file name patterns of test reports and package manager debug logs within a project
+	reportFiles = []string{"junit.xml", "npm-debug.log*", "yarn-debug.log*", "yarn-error.log*", "pnpm-debug.log*", "lerna-debug.log*"}
+)
+
+// removeReports removes test and coverage reports from the project directory.
+// Only the well-known report names are removed, never arbitrary log files.
+func (w *walker) removeReports(dir string) error {
+	entries, err := fileSystem.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read file entries of directory %q: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if !isReport(entry) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := w.record(path, "reports"); err != nil {
+			return err
+		}
+		if w.dry {
+			continue
+		}
+		if err := removeAll(path); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func isReport(entry os.FileInfo) bool {
+	if entry.IsDir() {
+		for _, name := range reportDirs {
+			if entry.Name() == name {
+				return true
+			}
+		}
+		return false
+	}
+	if !entry.Mode().IsRegular() {
+		return false
+	}
+	for _, pattern := range reportFiles {
+		if ok, _ := filepath.Match(pattern, entry.Name()); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package purge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRemoveReports(t *testing.T) {
+	files := []string{
+		"app/package.json",
+		"app/coverage/lcov.info",
+		"app/.nyc_output/out.json",
+		"app/junit.xml",
+		"app/npm-debug.log.123",
+		"app/yarn-error.log",
+		"app/server.log",
+		"app/src/junit.xml",
+		"docs/coverage/index.html",
+		"docs/junit.xml",
+	}
+	// only the reports of the project itself - neither arbitrary logs, nor reports of other directories
+	reports := []string{
+		"app/.nyc_output/out.json",
+		"app/coverage/lcov.info",
+		"app/junit.xml",
+		"app/npm-debug.log.123",
+		"app/yarn-error.log",
+	}
+	tests := []struct {
+		name         string
+		cleanReports bool
+		dry          bool
+		removed      []string
+	}{
+		{"disabled", false, false, nil},
+		{"enabled", true, false, reports},
+		{"dry run", true, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			var out bytes.Buffer
+			w := &walker{tasks: []Task{testDeps()}, root: dir, out: &out, maxDepth: -1, cleanReports: tt.cleanReports, dry: tt.dry}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			var removed []string
+			for _, name := range files {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+					removed = append(removed, name)
+				}
+			}
+			sort.Strings(removed)
+			if !reflect.DeepEqual(removed, tt.removed) {
+				t.Errorf("walk() removed %q, want %q", removed, tt.removed)
+			}
+			// reports are printed like the processed projects
+			if want := tt.cleanReports; strings.Contains(out.String(), filepath.Join(dir, "app", "coverage")) != want {
+				t.Errorf("walk() printed %q", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,484 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ErrNoTasks is returned by Run, if none of the selected runners is available on this system.
+var ErrNoTasks = errors.New("no available runners found, check the selected tools")
+
+// ErrAborted is returned by Run, if the purge was not confirmed.
+var ErrAborted = errors.New("aborted, nothing was removed")
+
+// Config holds the settings of Run. Each field corresponds to the flag of the same name of the command line app,
+// so see its help for the details. The zero value of a field disables its feature, unless noted otherwise.
+type Config struct {
+	Roots  []string  // absolute paths of the directories to purge, at least one is required - symbolic links are resolved
+	Tools  []string  // names of the runners to use, all available runners if empty
+	Stdout io.Writer // receives the processed paths, os.Stdout if nil
+	Stderr io.Writer // receives errors, notes and reports, os.Stderr if nil
+	// Confirm is asked before anything is removed, after the matches were listed. A nil Confirm doesn't ask.
+	Confirm func(question string) bool
+	// FileSystem is walked and cleaned instead of the file system of the operating system, if set.
+	// External commands and the global caches always work on the file system of the operating system.
+	FileSystem FileSystem
+
+	Dry       bool
+	Verbose   bool
+	Depth     int // 0 processes the roots only, negative means unlimited
+	Jobs      int // values below 1 run one project at a time
+	KeepGoing bool
+	FailFast  bool // stop at the first root which fails, if there are several
+	MaxErrors int
+	Timeout   time.Duration
+
+	// selection of projects
+	Exclude           []string
+	Include           []string
+	RootMarkers       []string // names of files or directories, e.g. .git, whose directory is not descended below
+	GitIdle           time.Duration
+	Since             time.Duration
+	MinSize           int64 // bytes
+	LimitPerTool      int
+	SkipSubmodules    bool
+	RespectGitTracked bool
+
+	// cleaning of projects
+	Deep               bool
+	CargoCleanMode     string // full if empty
+	CargoArgs          []string
+	DotnetArgs         []string
+	GoBin              bool
+	CleanBuildOutput   bool
+	CleanDockerContext bool
+	CleanReports       bool
+	Reinstall          bool
+	Sandbox            bool
+	IORate             int64 // bytes per second
+	SimulateErrorRate  float64
+	SimulateErrorSeed  int64
+
+	// output and reports
+	Realpath         bool
+	Print0           bool
+	Color            bool
+	JSON             bool
+	JSONArray        bool
+	ShowSkipped      bool
+	ShowSizes        bool
+	Progress         bool
+	ProgressInterval time.Duration // report scanned directories and cleaned projects this often on stderr, disabled if 0
+	ProgressInPlace  bool          // overwrite the periodic report in place, for terminals only
+	Tree             bool
+	ByTool           bool
+	AgeHistogram     bool
+	FreeSpace        bool
+	ReportDuplicates bool
+	FixBinLinks      bool
+
+	// global caches and other clean ups after the walk
+	SkipCache            bool
+	CacheOnly            bool
+	JSGlobalAll          bool
+	SystemCaches         bool
+	StrictGlobal         bool
+	CleanBrokenSymlinks  bool
+	ReportComposerGlobal bool
+	CleanComposerGlobal  bool
+	GoCleanDownloadTmp   bool
+}
+
+// Run purges the roots of the config like the command line app: it walks each root, cleans the matched projects,
+// prints the requested reports and clears the global caches of all installed tools afterwards.
+// A canceled context stops the walk after the current project, the reports cover the projects processed so far.
+func Run(ctx context.Context, cfg Config) error {
+	runMu.Lock()
+	defer runMu.Unlock()
+	if len(cfg.Roots) == 0 {
+		return errors.New("no path to purge given")
+	}
+	// the walk never leaves a root, so a root behind a symbolic link has to be resolved first
+	roots := make([]string, 0, len(cfg.Roots))
+	for _, root := range cfg.Roots {
+		if cfg.FileSystem != nil {
+			// the roots don't exist on the file system of the operating system
+			roots = append(roots, root)
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return fmt.Errorf("failed to resolve given path %s: %w", root, err)
+		}
+		roots = append(roots, resolved)
+	}
+	cfg.Roots = roots
+	configure(cfg)
+	// the first path stands in for all others where a single one is needed, e.g. for the free space of its filesystem
+	absPath := cfg.Roots[0]
+	options = runnerOptions{
+		root:               absPath,
+		deep:               cfg.Deep,
+		cargoCleanMode:     cfg.CargoCleanMode,
+		cargoArgs:          cfg.CargoArgs,
+		dotnetArgs:         cfg.DotnetArgs,
+		goBin:              cfg.GoBin,
+		cleanBuildOutput:   cfg.CleanBuildOutput,
+		cleanDockerContext: cfg.CleanDockerContext,
+	}
+	if options.cargoCleanMode == "" {
+		options.cargoCleanMode = "full"
+	}
+
+	tools := map[string]bool{}
+	for _, name := range cfg.Tools {
+		tools[name] = true
+	}
+	var tasks = []Task{}
+	for _, t := range DefaultTasks() {
+		if len(tools) == 0 || tools[t.Name()] {
+			tasks = append(tasks, t)
+		}
+	}
+	if cfg.Dry {
+		// replace all ops with a no-op when flag --dry is set - the walk already prints each match
+		tasks = dryTasks(tasks)
+	}
+
+	dups := &dupReport{}
+	if cfg.ReportDuplicates {
+		// replace all clean up tasks with the analysis of node_modules directories
+		tasks = []Task{runner{
+			name: "duplicates",
+			available: func() bool {
+				return true
+			},
+			matches: func(s string) bool {
+				return s == "package.json"
+			},
+			run:       dups.add,
+			artifacts: []string{"node_modules"},
+		}}
+	}
+
+	binLinks := &binLinkFixer{out: stderr, dry: cfg.Dry}
+	if cfg.FixBinLinks {
+		// replace all clean up tasks with the repair of node_modules/.bin directories
+		tasks = []Task{runner{
+			name: "bin-links",
+			available: func() bool {
+				return true
+			},
+			matches: func(s string) bool {
+				return s == "package.json"
+			},
+			run:       binLinks.fix,
+			artifacts: []string{"node_modules"},
+		}}
+	}
+
+	// no tasks no worries
+	if len(tasks) == 0 {
+		return ErrNoTasks
+	}
+
+	w := &walker{
+		ctx:          ctx,
+		tasks:        tasks,
+		root:         absPath,
+		out:          stdout,
+		dry:          cfg.Dry,
+		realpath:     cfg.Realpath,
+		print0:       cfg.Print0,
+		color:        cfg.Color && !cfg.JSON && !cfg.JSONArray && !cfg.Print0,
+		reinstall:    cfg.Reinstall,
+		cleanReports: cfg.CleanReports,
+		skipSubs:     cfg.SkipSubmodules,
+		keepTracked:  cfg.RespectGitTracked,
+		gitIdle:      cfg.GitIdle,
+		since:        cfg.Since,
+		minSize:      cfg.MinSize,
+		maxErrors:    cfg.MaxErrors,
+		keepGoing:    cfg.KeepGoing,
+		limitPerTool: cfg.LimitPerTool,
+		maxDepth:     cfg.Depth,
+		exclude:      cfg.Exclude,
+		include:      cfg.Include,
+		rootMarkers:  cfg.RootMarkers,
+		showSizes:    cfg.ShowSizes,
+		jsonLines:    cfg.JSON,
+	}
+	if !cfg.Dry && cfg.Confirm != nil && !cfg.CacheOnly {
+		// a mistyped path purges the wrong tree, so show what is going to be removed first
+		for _, root := range cfg.Roots {
+			if err := w.preview(root); err != nil {
+				return fmt.Errorf("previewing the purge failed with an error: %w", err)
+			}
+		}
+		if !cfg.Confirm("Proceed?") {
+			return ErrAborted
+		}
+	}
+	if cfg.Tree {
+		w.tree = newSizeTree(absPath)
+	}
+	if cfg.ByTool {
+		w.byTool = usageByTool{}
+	}
+	if cfg.AgeHistogram {
+		w.ages = newAgeHistogram()
+	}
+	if cfg.JSONArray {
+		w.doc = newJSONDocument()
+	}
+	if cfg.CacheOnly {
+		return purgeCaches(cfg)
+	}
+	var freeBefore uint64
+	if cfg.FreeSpace {
+		w.sizes = true
+		var err error
+		if freeBefore, err = queryFreeSpace(absPath); err != nil {
+			return err
+		}
+	}
+	if cfg.Progress {
+		for _, root := range cfg.Roots {
+			w.root = root
+			n, err := w.count(root, 0)
+			if err != nil {
+				return fmt.Errorf("counting directories failed with an error: %w", err)
+			}
+			w.total += n
+		}
+	}
+	// the analysis of -report-duplicates and the count of -fix-bin-links aren't safe for concurrent use
+	if cfg.Jobs > 1 && !cfg.ReportDuplicates && !cfg.FixBinLinks {
+		w.pool = newWorkerPool(cfg.Jobs)
+	}
+	// the percentage of -progress already reports on each directory
+	if cfg.ProgressInterval > 0 && !cfg.Progress {
+		w.status = startStatus(w, stderr, cfg.ProgressInterval, cfg.ProgressInPlace)
+	}
+	var err error
+	for _, root := range cfg.Roots {
+		w.root, options.root = root, root
+		err = w.walk(root, 0)
+		if err != nil && len(cfg.Roots) > 1 && !cfg.FailFast && !errors.Is(err, errInterrupted) {
+			// carry on with the next path, unless this was one failure too many
+			err = w.fail(fmt.Errorf("purging %s failed with an error: %w", root, err))
+		}
+		if err != nil {
+			break
+		}
+	}
+	if w.pool != nil {
+		if err != nil {
+			w.pool.stop(err)
+		}
+		// the first error of a worker wins, it happened before the walk noticed
+		if poolErr := w.pool.wait(); poolErr != nil {
+			err = poolErr
+		}
+	}
+	if w.status != nil {
+		w.status.close()
+	}
+	// the clean ups after the walk print paths, too, which have to come before the summary - the last line of the JSON output
+	var afterErr error
+	if err == nil {
+		afterErr = w.afterWalk(cfg)
+	}
+	// an interrupted walk still reports on all projects processed so far
+	interrupted := errors.Is(err, errInterrupted)
+	partial := err == nil || interrupted
+	if cfg.ShowSkipped {
+		printSkipped(stderr, w.skipped)
+	}
+	if cfg.ReportDuplicates && partial {
+		dups.print(stderr)
+	}
+	if cfg.Tree && partial {
+		w.tree.print(stderr)
+	}
+	if cfg.ByTool && partial {
+		w.byTool.print(stderr)
+	}
+	if cfg.AgeHistogram && partial {
+		w.ages.print(stderr)
+	}
+	if w.jsonLines && partial {
+		if err := writeJSONSummary(stdout, w.results, interrupted); err != nil {
+			return fmt.Errorf("writing JSON summary failed with an error: %w", err)
+		}
+	} else if partial {
+		w.results.printSummary(stderr, w.measuring(), interrupted)
+	}
+	if cfg.ShowSizes && partial {
+		if cfg.Dry {
+			fmt.Fprintf(stderr, "%10s  reclaimable in total\n", formatBytes(w.reclaimed))
+		} else {
+			fmt.Fprintf(stderr, "%10s  reclaimed in total\n", formatBytes(w.reclaimed))
+		}
+	}
+	if err != nil {
+		if w.doc != nil {
+			w.doc.write(stdout, w, err)
+		}
+		return fmt.Errorf("purging failed with an error: %w", err)
+	}
+	if afterErr != nil {
+		if w.doc != nil {
+			w.doc.write(stdout, w, afterErr)
+		}
+		return afterErr
+	}
+	if cfg.FixBinLinks {
+		fmt.Fprintf(stderr, "removed %d broken links\n", binLinks.fixed)
+	}
+	// repairs leave the global caches alone
+	if !cfg.Dry && !cfg.FixBinLinks && !cfg.SkipCache {
+		if err := purgeCaches(cfg); err != nil {
+			return err
+		}
+	}
+	if cfg.FreeSpace {
+		freeAfter, err := queryFreeSpace(absPath)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stderr, freeSpaceDelta(freeBefore, freeAfter, w.measured))
+	}
+	if w.doc != nil {
+		if err := w.doc.write(stdout, w); err != nil {
+			return fmt.Errorf("writing JSON document failed with an error: %w", err)
+		}
+	}
+	if len(w.failures) > 0 {
+		return fmt.Errorf("purging finished with %d %s", len(w.failures), plural(len(w.failures), "error", "errors"))
+	}
+	return nil
+}
+
+// runMu serializes Run and Walk, which share the package state set by configure.
+var runMu sync.Mutex
+
+// configure sets the package state read by the walk and the runners from cfg.
+// Each field is set on every call, so nothing leaks from one Run into the next.
+func configure(cfg Config) {
+	stdout = &syncWriter{w: os.Stdout}
+	if cfg.Stdout != nil {
+		stdout = &syncWriter{w: cfg.Stdout}
+	}
+	stderr = &syncWriter{w: os.Stderr}
+	if cfg.Stderr != nil {
+		stderr = &syncWriter{w: cfg.Stderr}
+	}
+	verbose = nil
+	if cfg.Verbose {
+		verbose = stderr
+	}
+	ioLimiter = nil
+	if cfg.IORate > 0 {
+		ioLimiter = newTokenBucket(cfg.IORate)
+	}
+	simulatedErrors = nil
+	if cfg.SimulateErrorRate > 0 {
+		simulatedErrors = newErrorInjector(cfg.SimulateErrorRate, cfg.SimulateErrorSeed)
+	}
+	fileSystem = osFileSystem{}
+	if cfg.FileSystem != nil {
+		fileSystem = cfg.FileSystem
+	}
+	commandTimeout = cfg.Timeout
+	commandEnv = nil
+	if cfg.Sandbox {
+		commandEnv = sandboxEnv(os.Environ())
+	}
+}
+
+// afterWalk runs the clean ups of a successful walk which don't belong to any project.
+func (w *walker) afterWalk(cfg Config) error {
+	if cfg.CleanBrokenSymlinks {
+		for _, root := range cfg.Roots {
+			if err := w.removeBrokenSymlinks(root); err != nil {
+				return fmt.Errorf("removing broken symbolic links failed with an error: %w", err)
+			}
+		}
+	}
+	if cfg.ReportComposerGlobal || cfg.CleanComposerGlobal {
+		if err := w.purgeComposerGlobal(cfg.ReportComposerGlobal, cfg.CleanComposerGlobal); err != nil {
+			return fmt.Errorf("purging composer global vendor directory failed with an error: %w", err)
+		}
+	}
+	if cfg.GoCleanDownloadTmp {
+		modcache, err := goModCache()
+		if err == nil {
+			err = w.cleanGoDownloadTmp(modcache)
+		}
+		if err != nil {
+			return fmt.Errorf("cleaning go module downloads failed with an error: %w", err)
+		}
+	}
+	return nil
+}
+
+// purgeCaches clears the global caches of all installed tools.
+// The projects are purged already, so a failing global cache is no reason to fail the whole run - unless StrictGlobal is set.
+func purgeCaches(cfg Config) error {
+	cleaners := []cacheCleaner{
+		{name: "go cache", clear: clearCachesGo},
+		{name: "composer cache", clear: clearCachesComposer},
+	}
+	if cfg.JSGlobalAll {
+		cleaners = append(cleaners, cacheCleaner{name: "javascript caches", clear: clearCachesJS})
+	} else {
+		cleaners = append(cleaners, cacheCleaner{name: "npm cache", clear: clearCachesNpm})
+	}
+	cleaners = append(cleaners, cacheCleaner{name: "temporary install directories", clear: clearCachesTempInstallers})
+	if _, err := exec.LookPath(appName("cargo")); err == nil {
+		cleaners = append(cleaners, cacheCleaner{name: "cargo registry", clear: func() error {
+			return clearCachesCargoRegistry(cfg.Deep)
+		}})
+	}
+	if _, err := exec.LookPath("ansible-galaxy"); err == nil {
+		cleaners = append(cleaners, cacheCleaner{name: "ansible cache", clear: clearCachesAnsible})
+	}
+	if _, err := exec.LookPath("bundle"); err == nil {
+		cleaners = append(cleaners, cacheCleaner{name: "ruby gems", clear: clearCachesBundler})
+	}
+	if _, err := exec.LookPath("mix"); err == nil {
+		cleaners = append(cleaners, cacheCleaner{name: "hex packages", clear: clearCachesMix})
+	}
+	if _, err := exec.LookPath(appName("helm")); err == nil {
+		cleaners = append(cleaners, cacheCleaner{name: "helm cache", clear: clearCachesHelm})
+	}
+	if runtime.GOOS == "darwin" {
+		cleaners = append(cleaners, cacheCleaner{name: "xcode derived data", clear: clearCachesXcode})
+	}
+	if cfg.SystemCaches {
+		cleaners = append(cleaners, cacheCleaner{name: "system caches", clear: clearCachesSystem})
+	}
+	var cacheFailures int
+	for _, c := range cleaners {
+		if err := c.clear(); err != nil {
+			if cfg.StrictGlobal {
+				return fmt.Errorf("purging %s failed with an error: %w", c.name, err)
+			}
+			fmt.Fprintf(stderr, "purging %s failed with an error: %v\n", c.name, err)
+			cacheFailures++
+		}
+	}
+	if cacheFailures > 0 {
+		fmt.Fprintf(stderr, "purging %d of %d global caches failed (use -strict-global to treat this as an error)\n", cacheFailures, len(cleaners))
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package purge_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/denisbrodbeck/purge-npm/purge"
+)
+
+// newExtension creates a browser extension with packaged builds below a new temporary directory
+// and returns the resolved directory, which the returned function removes again.
+func newExtension(t *testing.T) (string, func()) {
+	t.Helper()
+	root, err := ioutil.TempDir("", "purge-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		t.Fatal(err)
+	}
+	ext := filepath.Join(root, "extension")
+	if err := os.MkdirAll(filepath.Join(ext, "web-ext-artifacts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"manifest_version": 2, "name": "test", "version": "1.0"}`
+	if err := ioutil.WriteFile(filepath.Join(ext, "manifest.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root, func() { os.RemoveAll(root) }
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *purge.Config, root string)
+		wantErr   error
+		removed   bool
+		printed   bool
+	}{
+		{
+			name:      "dry run",
+			configure: func(cfg *purge.Config, root string) { cfg.Dry = true },
+			printed:   true,
+		},
+		{
+			name:      "purge",
+			configure: func(cfg *purge.Config, root string) {},
+			removed:   true,
+			printed:   true,
+		},
+		{
+			name: "root behind a symbolic link",
+			configure: func(cfg *purge.Config, root string) {
+				link := filepath.Join(root, "link")
+				if err := os.Symlink(filepath.Join(root, "extension"), link); err != nil {
+					t.Skip(err)
+				}
+				cfg.Roots = []string{link}
+			},
+			removed: true,
+			printed: true,
+		},
+		{
+			name: "declined confirmation",
+			configure: func(cfg *purge.Config, root string) {
+				cfg.Confirm = func(string) bool { return false }
+			},
+			wantErr: purge.ErrAborted,
+		},
+		{
+			name:      "unknown tool",
+			configure: func(cfg *purge.Config, root string) { cfg.Tools = []string{"no-such-tool"} },
+			wantErr:   purge.ErrNoTasks,
+		},
+		{
+			name:      "depth limit",
+			configure: func(cfg *purge.Config, root string) { cfg.Depth = 0 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, cleanup := newExtension(t)
+			defer cleanup()
+			var out bytes.Buffer
+			cfg := purge.Config{
+				Roots:     []string{root},
+				Tools:     []string{"webext"},
+				Stdout:    &out,
+				Stderr:    ioutil.Discard,
+				Depth:     -1,
+				SkipCache: true,
+			}
+			tt.configure(&cfg, root)
+			err := purge.Run(context.Background(), cfg)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() = %v, want %v", err, tt.wantErr)
+			}
+			_, statErr := os.Stat(filepath.Join(root, "extension", "web-ext-artifacts"))
+			if removed := os.IsNotExist(statErr); removed != tt.removed {
+				t.Errorf("web-ext-artifacts removed = %v, want %v", removed, tt.removed)
+			}
+			// printed paths are always below the resolved root
+			want := filepath.Join(root, "extension", "manifest.json") + "\n"
+			if printed := out.String() == want; printed != tt.printed {
+				t.Errorf("Run() printed %q, want %v", out.String(), tt.printed)
+			}
+		})
+	}
+}
+
+func TestRunNoRoots(t *testing.T) {
+	if err := purge.Run(context.Background(), purge.Config{Stderr: ioutil.Discard}); err == nil {
+		t.Error("Run() without roots succeeded")
+	}
+}
+
+func TestRunResetsState(t *testing.T) {
+	root, cleanup := newExtension(t)
+	defer cleanup()
+	var first, second bytes.Buffer
+	cfg := purge.Config{
+		Roots:     []string{root},
+		Tools:     []string{"webext"},
+		Stdout:    ioutil.Discard,
+		Stderr:    &first,
+		Dry:       true,
+		Depth:     -1,
+		SkipCache: true,
+		Verbose:   true,
+	}
+	if err := purge.Run(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(first.String(), "entering") {
+		t.Fatalf("verbose Run() logged %q", first.String())
+	}
+	logged := first.Len()
+	cfg.Stderr, cfg.Verbose = &second, false
+	if err := purge.Run(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if first.Len() != logged || strings.Contains(second.String(), "entering") {
+		t.Errorf("Run() kept logging of an earlier run")
+	}
+}
+
+func TestRunPreviewsEachRoot(t *testing.T) {
+	first, cleanupFirst := newExtension(t)
+	defer cleanupFirst()
+	second, cleanupSecond := newExtension(t)
+	defer cleanupSecond()
+	var stderr bytes.Buffer
+	err := purge.Run(context.Background(), purge.Config{
+		Roots:     []string{first, second},
+		Tools:     []string{"webext"},
+		Stdout:    ioutil.Discard,
+		Stderr:    &stderr,
+		Confirm:   func(string) bool { return false },
+		Depth:     -1,
+		SkipCache: true,
+	})
+	if !errors.Is(err, purge.ErrAborted) {
+		t.Fatalf("Run() = %v, want %v", err, purge.ErrAborted)
+	}
+	if n := strings.Count(stderr.String(), "would purge 1 project"); n != 2 {
+		t.Errorf("Run() previewed %d of 2 roots:\n%s", n, stderr.String())
+	}
+}
+
+func TestRunSymlinkedRoot(t *testing.T) {
+	root, cleanup := newExtension(t)
+	defer cleanup()
+	// the root is followed, links within the tree aren't
+	outside, cleanupOutside := newExtension(t)
+	defer cleanupOutside()
+	projects := filepath.Join(root, "projects")
+	if err := os.Symlink(root, projects); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "outside")); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err := purge.Run(context.Background(), purge.Config{
+		Roots:     []string{projects},
+		Tools:     []string{"webext"},
+		Stdout:    &out,
+		Stderr:    ioutil.Discard,
+		Depth:     -1,
+		SkipCache: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "extension", "manifest.json") + "\n"; out.String() != want {
+		t.Errorf("Run() printed %q, want %q", out.String(), want)
+	}
+	if _, err := os.Stat(filepath.Join(root, "extension", "web-ext-artifacts")); !os.IsNotExist(err) {
+		t.Errorf("web-ext-artifacts below the resolved root was not removed")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "extension", "web-ext-artifacts")); err != nil {
+		t.Errorf("web-ext-artifacts behind a symbolic link within the tree was removed")
+	}
+}
+
+func TestRunPrint0(t *testing.T) {
+	root, cleanup := newExtension(t)
+	defer cleanup()
+	// a second extension with spaces in its path
+	spaced := filepath.Join(root, "my extension")
+	if err := os.MkdirAll(filepath.Join(spaced, "web-ext-artifacts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"manifest_version": 3, "name": "spaced", "version": "1.0"}`
+	if err := ioutil.WriteFile(filepath.Join(spaced, "manifest.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err := purge.Run(context.Background(), purge.Config{
+		Roots:     []string{root},
+		Tools:     []string{"webext"},
+		Stdout:    &out,
+		Stderr:    ioutil.Discard,
+		Dry:       true,
+		Depth:     -1,
+		SkipCache: true,
+		Print0:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(root, "extension", "manifest.json") + "\x00" + filepath.Join(spaced, "manifest.json") + "\x00"
+	if out.String() != want {
+		t.Errorf("Run() printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunJSONArray(t *testing.T) {
+	tests := []struct {
+		name string
+		dry  bool
+	}{
+		{"dry run", true},
+		{"purge", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, cleanup := newExtension(t)
+			defer cleanup()
+			var out bytes.Buffer
+			err := purge.Run(context.Background(), purge.Config{
+				Roots:     []string{root},
+				Tools:     []string{"webext"},
+				Stdout:    &out,
+				Stderr:    ioutil.Discard,
+				Dry:       tt.dry,
+				Depth:     -1,
+				SkipCache: true,
+				JSONArray: true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Matches []struct {
+					Path string `json:"path"`
+				} `json:"matches"`
+				Summary struct {
+					Matches int  `json:"matches"`
+					Errors  int  `json:"errors"`
+					Dry     bool `json:"dry"`
+				} `json:"summary"`
+				Errors []struct {
+					Error string `json:"error"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+				t.Fatalf("Run() printed an invalid document: %v\n%s", err, out.String())
+			}
+			want := filepath.Join(root, "extension")
+			if len(doc.Matches) != 1 || doc.Matches[0].Path != want {
+				t.Errorf("matches = %+v, want %s", doc.Matches, want)
+			}
+			if doc.Summary.Matches != 1 || doc.Summary.Errors != 0 || doc.Summary.Dry != tt.dry || doc.Errors == nil {
+				t.Errorf("summary = %+v and errors %q", doc.Summary, doc.Errors)
+			}
+		})
+	}
+}
+
+// cancelWriter cancels a context once a write contains the trigger.
+type cancelWriter struct {
+	bytes.Buffer
+	trigger string
+	cancel  context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), w.trigger) {
+		w.cancel()
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestRunInterrupted(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *purge.Config)
+		check     func(t *testing.T, stdout, stderr string)
+	}{
+		{
+			name:      "text",
+			configure: func(cfg *purge.Config) {},
+			check: func(t *testing.T, stdout, stderr string) {
+				if !strings.Contains(stderr, "webext: 1 project\ninterrupted: the summary covers the projects processed so far\n") {
+					t.Errorf("Run() printed the summary %q", stderr)
+				}
+			},
+		},
+		{
+			name:      "json lines",
+			configure: func(cfg *purge.Config) { cfg.JSON = true },
+			check: func(t *testing.T, stdout, stderr string) {
+				lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+				want := `{"summary":[{"tool":"webext","projects":1,"bytes":0,"failed":0}],"interrupted":true}`
+				if last := lines[len(lines)-1]; last != want {
+					t.Errorf("Run() printed the summary %s, want %s", last, want)
+				}
+			},
+		},
+		{
+			name:      "json array",
+			configure: func(cfg *purge.Config) { cfg.JSONArray = true },
+			check: func(t *testing.T, stdout, stderr string) {
+				var doc struct {
+					Summary struct {
+						Matches     int  `json:"matches"`
+						Interrupted bool `json:"interrupted"`
+					} `json:"summary"`
+				}
+				if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+					t.Fatalf("Run() printed an invalid document: %v\n%s", err, stdout)
+				}
+				if doc.Summary.Matches != 1 || !doc.Summary.Interrupted {
+					t.Errorf("summary = %+v, want 1 match and the interrupted marker", doc.Summary)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, cleanup := newExtension(t)
+			defer cleanup()
+			// the second extension is never reached
+			second := filepath.Join(root, "second")
+			if err := os.MkdirAll(filepath.Join(second, "web-ext-artifacts"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			manifest := `{"manifest_version": 2, "name": "second", "version": "1.0"}`
+			if err := ioutil.WriteFile(filepath.Join(second, "manifest.json"), []byte(manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			// the verbose log of the first extension interrupts the run
+			var out bytes.Buffer
+			stderr := &cancelWriter{trigger: "entering " + filepath.Join(root, "extension"), cancel: cancel}
+			cfg := purge.Config{
+				Roots:     []string{root},
+				Tools:     []string{"webext"},
+				Stdout:    &out,
+				Stderr:    stderr,
+				Depth:     -1,
+				SkipCache: true,
+				Verbose:   true,
+			}
+			tt.configure(&cfg)
+			if err := purge.Run(ctx, cfg); err == nil || !strings.Contains(err.Error(), "interrupted") {
+				t.Fatalf("Run() = %v, want an interrupted run", err)
+			}
+			if _, err := os.Stat(filepath.Join(second, "web-ext-artifacts")); err != nil {
+				t.Errorf("Run() purged the second extension: %v", err)
+			}
+			tt.check(t, out.String(), stderr.String())
+		})
+	}
+}
@@ -0,0 +1,704 @@
+package purge
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// runnerOptions are the settings of the command line, which change how runners clean a project.
+type runnerOptions struct {
+	root               string // absolute start path of the walk
+	deep               bool
+	cargoCleanMode     string
+	cargoArgs          []string
+	dotnetArgs         []string
+	goBin              bool
+	cleanBuildOutput   bool // enables the opt-in build-output runner
+	cleanDockerContext bool // enables the opt-in docker-context runner
+}
+
+// options are read by the runners of the registry when they run. main sets them before the walk.
+var options = runnerOptions{cargoCleanMode: "full"}
+
+// registry lists all runners in the order they claim files.
+var registry = []runner{
+	{
+		name:     "composer",
+		patterns: []string{"composer.json"},
+		available: func() bool {
+			_, err := exec.LookPath("composer")
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "composer.json"
+		},
+		verify: isComposerProject,
+		run: func(path string) error {
+			dir := filepath.Join(filepath.Dir(path), "vendor")
+			if err := removeAll(dir); err != nil {
+				return fmt.Errorf("failed to remove path %s: %w", dir, err)
+			}
+			return nil
+		},
+		reinstall: func(path string) *exec.Cmd {
+			cmd := exec.Command("composer", "install", "--no-interaction")
+			cmd.Dir = filepath.Dir(path)
+			return cmd
+		},
+		manifest:  "composer.json",
+		artifacts: []string{"vendor"},
+	},
+	{
+		name:     "pnpm",
+		patterns: []string{"package.json"},
+		available: func() bool {
+			_, err := exec.LookPath("pnpm")
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "package.json"
+		},
+		// all node package managers match package.json, so each project is left to the one which owns its lock file
+		verify: func(path string) bool {
+			return isNodeProjectOf(path, "pnpm-lock.yaml")
+		},
+		run: func(path string) error {
+			dir := filepath.Join(filepath.Dir(path), "node_modules")
+			if err := removeAll(dir); err != nil {
+				return fmt.Errorf("failed to remove path %s: %w", dir, err)
+			}
+			return nil
+		},
+		reinstall: func(path string) *exec.Cmd {
+			cmd := exec.Command("pnpm", "install")
+			if _, err := fileSystem.Stat(filepath.Join(filepath.Dir(path), "pnpm-lock.yaml")); err == nil {
+				cmd = exec.Command("pnpm", "install", "--frozen-lockfile")
+			}
+			cmd.Dir = filepath.Dir(path)
+			return cmd
+		},
+		manifest:  "package.json",
+		artifacts: []string{"node_modules"},
+	},
+	{
+		name:     "yarn",
+		patterns: []string{"package.json"},
+		available: func() bool {
+			_, err := exec.LookPath("yarn")
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "package.json"
+		},
+		verify: func(path string) bool {
+			return isNodeProjectOf(path, "yarn.lock")
+		},
+		run: func(path string) error {
+			// yarn berry keeps a per-project cache of package archives
+			for _, name := range []string{"node_modules", filepath.Join(".yarn", "cache")} {
+				dir := filepath.Join(filepath.Dir(path), name)
+				if err := removeAll(dir); err != nil {
+					return fmt.Errorf("failed to remove path %s: %w", dir, err)
+				}
+			}
+			return nil
+		},
+		reinstall: func(path string) *exec.Cmd {
+			cmd := exec.Command("yarn", "install")
+			cmd.Dir = filepath.Dir(path)
+			return cmd
+		},
+		manifest:  "package.json",
+		artifacts: []string{"node_modules", ".yarn/cache"},
+	},
+	{
+		name:     "npm",
+		patterns: []string{"package.json"},
+		available: func() bool {
+			_, err := exec.LookPath("npm")
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "package.json"
+		},
+		run: func(path string) error {
+			dir := filepath.Join(filepath.Dir(path), "node_modules")
+			if err := removeAll(dir); err != nil {
+				return fmt.Errorf("failed to remove path %s: %w", dir, err)
+			}
+			return nil
+		},
+		reinstall: func(path string) *exec.Cmd {
+			// `npm ci` requires a lock file, so fall back to a regular install without one
+			cmd := exec.Command("npm", "install")
+			if _, err := fileSystem.Stat(filepath.Join(filepath.Dir(path), "package-lock.json")); err == nil {
+				cmd = exec.Command("npm", "ci")
+			}
+			cmd.Dir = filepath.Dir(path)
+			return cmd
+		},
+		manifest:  "package.json",
+		artifacts: []string{"node_modules"},
+	},
+	{
+		name:     "cargo",
+		patterns: []string{"Cargo.toml", "cargo.toml"},
+		available: func() bool {
+			_, err := exec.LookPath(appName("cargo"))
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "Cargo.toml" || s == "cargo.toml"
+		},
+		verify: func(path string) bool {
+			// members share the target directory of their workspace, which is cleaned at the workspace root
+			return !isCargoWorkspaceMember(path, options.root)
+		},
+		run: func(path string) error {
+			if options.cargoCleanMode != "full" {
+				return cleanCargoTarget(filepath.Dir(path), options.cargoCleanMode)
+			}
+			args := append([]string{"clean"}, options.cargoArgs...)
+			ctx, cancel := commandContext()
+			defer cancel()
+			cmd := exec.CommandContext(ctx, appName("cargo"), args...) // app will be found in PATH by `exec`
+			cmd.Dir = filepath.Dir(path)                               // set working dir
+			cmd.Env = commandEnv
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("failed to run command %q: %w", cmd.String(), commandError(ctx, err))
+			}
+			return nil
+		},
+		reinstall: func(path string) *exec.Cmd {
+			cmd := exec.Command(appName("cargo"), "fetch")
+			cmd.Dir = filepath.Dir(path)
+			return cmd
+		},
+		artifacts: []string{"target"},
+		command:   "cargo clean",
+	},
+	{
+		name:     "dotnet",
+		patterns: []string{"*.csproj", "*.sln"},
+		available: func() bool {
+			_, err := exec.LookPath(appName("dotnet"))
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return strings.HasSuffix(strings.ToLower(s), ".csproj") || strings.HasSuffix(strings.ToLower(s), ".sln")
+		},
+		run: func(path string) error {
+			args := append([]string{"clean", "--nologo"}, options.dotnetArgs...)
+			ctx, cancel := commandContext()
+			defer cancel()
+			cmd := exec.CommandContext(ctx, appName("dotnet"), args...) // app will be found in PATH by `exec`
+			cmd.Dir = filepath.Dir(path)                                // set working dir
+			cmd.Env = commandEnv
+			if out, err := cmd.CombinedOutput(); err != nil {
+				// this one fails (or hangs) often, because only dotnet core projects are supported,
+				// so remove the build output of all configurations directly
+				fmt.Fprintf(stderr, "failed to run command %q, removing bin and obj instead: %v\n%s\n", cmd.String(), commandError(ctx, err), string(out))
+				for _, name := range []string{"bin", "obj"} {
+					dir := filepath.Join(filepath.Dir(path), name)
+					if err := removeAll(dir); err != nil {
+						return fmt.Errorf("failed to remove path %s: %w", dir, err)
+					}
+				}
+			}
+			return nil
+		},
+		artifacts: []string{"bin", "obj"},
+		command:   "dotnet clean",
+	},
+	{
+		name:     "skaffold",
+		patterns: []string{"skaffold.yaml", "skaffold.yml"},
+		available: func() bool {
+			_, err := exec.LookPath(appName("skaffold"))
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "skaffold.yaml" || s == "skaffold.yml"
+		},
+		run: func(path string) error {
+			dir := filepath.Join(filepath.Dir(path), ".skaffold")
+			if err := removeAll(dir); err != nil {
+				return fmt.Errorf("failed to remove path %s: %w", dir, err)
+			}
+			return nil
+		},
+		manifest:  "skaffold.yaml",
+		artifacts: []string{".skaffold"},
+	},
+	{
+		name:     "flatpak",
+		patterns: []string{"*.yml", "*.yaml", "*.json"},
+		available: func() bool {
+			_, err := exec.LookPath("flatpak-builder")
+			return err == nil
+		},
+		matches: isFlatpakManifestName,
+		verify:  isFlatpakManifest,
+		run: func(path string) error {
+			dir := filepath.Join(filepath.Dir(path), ".flatpak-builder")
+			if err := removeAll(dir); err != nil {
+				return fmt.Errorf("failed to remove path %s: %w", dir, err)
+			}
+			return nil
+		},
+		manifest:  "org.example.App.yml",
+		artifacts: []string{".flatpak-builder"},
+	},
+	{
+		name:     "snapcraft",
+		patterns: []string{"snapcraft.yaml", ".snapcraft.yaml"},
+		available: func() bool {
+			_, err := exec.LookPath("snapcraft")
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "snapcraft.yaml" || s == ".snapcraft.yaml"
+		},
+		run:       removeSnapcraftBuild,
+		manifest:  "snapcraft.yaml",
+		artifacts: []string{"parts", "prime", "stage"},
+	},
+	{
+		name:     "ansible",
+		patterns: []string{"requirements.yml", "requirements.yaml", "molecule/*/molecule.yml"},
+		available: func() bool {
+			_, errGalaxy := exec.LookPath("ansible-galaxy")
+			_, errMolecule := exec.LookPath("molecule")
+			return errGalaxy == nil || errMolecule == nil
+		},
+		matches:   isAnsibleName,
+		verify:    isAnsibleProject,
+		run:       removeAnsibleCaches,
+		artifacts: []string{".ansible", ".molecule"},
+		manifest:  "requirements.yml",
+	},
+	{
+		name:     "bundler",
+		patterns: []string{"Gemfile"},
+		available: func() bool {
+			_, err := exec.LookPath("bundle")
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "Gemfile"
+		},
+		run:       removeBundlerVendor,
+		artifacts: []string{"vendor/bundle", ".bundle"},
+		manifest:  "Gemfile",
+	},
+	{
+		name:     "mix",
+		patterns: []string{"mix.exs"},
+		available: func() bool {
+			_, err := exec.LookPath("mix")
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "mix.exs"
+		},
+		run:       cleanMix,
+		artifacts: []string{"_build", "deps"},
+		command:   "mix clean",
+	},
+	{
+		name:     "stack",
+		patterns: []string{"stack.yaml"},
+		available: func() bool {
+			_, err := exec.LookPath(appName("stack"))
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "stack.yaml"
+		},
+		run:       cleanStack,
+		artifacts: []string{".stack-work"},
+		command:   "stack clean",
+	},
+	{
+		name:     "cabal",
+		patterns: []string{"*.cabal"},
+		available: func() bool {
+			_, err := exec.LookPath(appName("cabal"))
+			return err == nil
+		},
+		matches:   isCabalName,
+		run:       cleanCabal,
+		artifacts: []string{"dist-newstyle"},
+		command:   "cabal clean",
+	},
+	{
+		name:     "latex",
+		patterns: []string{"*.tex"},
+		available: func() bool {
+			for _, name := range []string{"latexmk", "pdflatex", "xelatex", "lualatex"} {
+				if _, err := exec.LookPath(name); err == nil {
+					return true
+				}
+			}
+			return false
+		},
+		matches:   isLatexName,
+		verify:    isLatexDocument,
+		run:       cleanLatex,
+		artifacts: []string{"build", "out"},
+		keeps:     keepsLatexOutput,
+		command:   "latexmk -C",
+	},
+	{
+		name:     "python",
+		patterns: []string{".python-version", "pyproject.toml", "requirements.txt", "setup.py"},
+		available: func() bool {
+			for _, name := range []string{"python3", "python", "pyenv"} {
+				if _, err := exec.LookPath(name); err == nil {
+					return true
+				}
+			}
+			return false
+		},
+		matches: func(s string) bool {
+			switch s {
+			case ".python-version", "pyproject.toml", "requirements.txt", "setup.py":
+				return true
+			}
+			return false
+		},
+		run: func(path string) error {
+			return removePythonVenv(path, options.deep)
+		},
+		artifacts: []string{".venv", "venv", "build", ".eggs"},
+		keeps:     keepsPythonArtifact,
+		manifest:  "pyproject.toml",
+	},
+	{
+		name:     "pycache",
+		patterns: []string{"__pycache__/", ".pytest_cache/", ".mypy_cache/"},
+		available: func() bool {
+			for _, name := range []string{"python3", "python"} {
+				if _, err := exec.LookPath(name); err == nil {
+					return true
+				}
+			}
+			return false
+		},
+		// the caches are matched by their own name instead of a file next to them
+		matches: func(s string) bool {
+			return false
+		},
+		matchesDir: isPythonCacheDir,
+		run:        removePythonCaches,
+		artifacts:  pythonCacheDirs,
+		// any file does, the caches need no project
+		manifest: "example.py",
+	},
+	{
+		name:     "cython",
+		patterns: []string{"*.pyx"},
+		available: func() bool {
+			for _, name := range []string{"python3", "python"} {
+				if _, err := exec.LookPath(name); err == nil {
+					return true
+				}
+			}
+			return false
+		},
+		matches: func(s string) bool {
+			return strings.HasSuffix(s, ".pyx")
+		},
+		verify: isCythonPackage,
+		run:    removeCythonOutput,
+	},
+	{
+		name:     "unreal",
+		patterns: []string{"*.uproject"},
+		available: func() bool {
+			// the engine is rarely in PATH, the project file is specific enough
+			return true
+		},
+		matches: isUnrealProjectName,
+		run: func(path string) error {
+			return removeUnrealCaches(path, options.deep)
+		},
+		artifacts: []string{"DerivedDataCache", "Intermediate", "Saved"},
+		manifest:  "Game.uproject",
+	},
+	{
+		name:     "go",
+		patterns: []string{"go.mod", "go.work"},
+		available: func() bool {
+			_, err := exec.LookPath(appName("go"))
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "go.mod" || s == "go.work"
+		},
+		run: func(path string) error {
+			return removeGoVendor(path, options.goBin)
+		},
+		artifacts: []string{"vendor"},
+		keeps:     keepsGoVendor,
+		extraArtifacts: func() []string {
+			if options.goBin {
+				return []string{"bin"}
+			}
+			return nil
+		},
+		manifest: "go.mod",
+	},
+	{
+		name:     "gradle",
+		patterns: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"},
+		available: func() bool {
+			// projects with a wrapper script only need a java runtime
+			_, errGradle := exec.LookPath("gradle")
+			_, errJava := exec.LookPath("java")
+			return errGradle == nil || errJava == nil
+		},
+		matches: func(s string) bool {
+			switch s {
+			case "build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts":
+				return true
+			}
+			return false
+		},
+		verify: canRunGradle,
+		run: func(path string) error {
+			return cleanGradle(path, options.deep)
+		},
+		artifacts: []string{"build", ".gradle/caches/build-cache-1", ".gradle/configuration-cache"},
+		command:   "gradle clean",
+	},
+	{
+		name:     "maven",
+		patterns: []string{"pom.xml"},
+		available: func() bool {
+			_, err := exec.LookPath("mvn")
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "pom.xml"
+		},
+		run:       cleanMaven,
+		artifacts: []string{"target"},
+		command:   "mvn clean",
+	},
+	{
+		name:     "cmake",
+		patterns: []string{"CMakeLists.txt"},
+		available: func() bool {
+			_, err := exec.LookPath(appName("cmake"))
+			return err == nil
+		},
+		matches: func(s string) bool {
+			return s == "CMakeLists.txt"
+		},
+		run:       removeCMakeBuild,
+		artifacts: cmakeBuildDirs,
+		keeps:     keepsCMakeBuild,
+		manifest:  "CMakeLists.txt",
+	},
+	{
+		name:     "xcode",
+		patterns: []string{"*.xcodeproj/", "*.xcworkspace/"},
+		available: func() bool {
+			return runtime.GOOS == "darwin"
+		},
+		// the projects are bundles, which are directories
+		matches: func(s string) bool {
+			return false
+		},
+		matchesDir: isXcodeProjectDir,
+		// the build products live in DerivedData outside of the project
+		run: removeXcodeBuild,
+	},
+	{
+		name:     "terraform",
+		patterns: []string{"*.tf"},
+		available: func() bool {
+			_, err := exec.LookPath(appName("terraform"))
+			return err == nil
+		},
+		matches:   isTerraformName,
+		run:       removeTerraform,
+		artifacts: []string{".terraform"},
+		manifest:  "main.tf",
+	},
+	{
+		name:     "webext",
+		patterns: []string{"manifest.json"},
+		available: func() bool {
+			return true
+		},
+		matches: func(s string) bool {
+			return s == "manifest.json"
+		},
+		verify: isWebExtensionManifest,
+		run: func(path string) error {
+			names := []string{"web-ext-artifacts"}
+			if options.cleanBuildOutput {
+				names = append(names, webExtBuildDirs...)
+			}
+			for _, name := range names {
+				dir := filepath.Join(filepath.Dir(path), name)
+				if err := removeAll(dir); err != nil {
+					return fmt.Errorf("failed to remove path %s: %w", dir, err)
+				}
+			}
+			return nil
+		},
+		artifacts: []string{"web-ext-artifacts"},
+		// the bundler output may hold the only copy of hand-written sources, like the build output of vite and rollup
+		extraArtifacts: func() []string {
+			if options.cleanBuildOutput {
+				return webExtBuildDirs
+			}
+			return nil
+		},
+		manifest: "manifest.json",
+	},
+	{
+		name:     "monorepo",
+		patterns: monorepoMarkers,
+		available: func() bool {
+			return true
+		},
+		matches:   isMonorepoMarker,
+		run:       removeMonorepoCaches,
+		artifacts: []string{".nx", ".turbo", "common/temp"},
+		manifest:  "nx.json",
+	},
+	{
+		name:     "bazel",
+		patterns: bazelMarkers,
+		available: func() bool {
+			// without bazel the outputs behind the convenience symlinks are removed directly
+			return true
+		},
+		matches: isBazelMarker,
+		run:     cleanBazel,
+		command: "bazel clean",
+	},
+	// opt-in runners
+	{
+		name:     "build-output",
+		patterns: []string{"vite.config.*", "rollup.config.*"},
+		available: func() bool {
+			return options.cleanBuildOutput
+		},
+		matches:   isBuildConfig,
+		run:       removeBuildOutput,
+		manifest:  "vite.config.js",
+		artifacts: []string{"dist"},
+	},
+	{
+		name:     "docker-context",
+		patterns: []string{"Dockerfile"},
+		available: func() bool {
+			return options.cleanDockerContext
+		},
+		matches: func(s string) bool {
+			return s == "Dockerfile"
+		},
+		// without a list of directories there's nothing to do, so leave the directory to other runners
+		verify: hasDockerPurgeFile,
+		run:    removeDockerContext,
+	},
+}
+
+// DefaultTasks returns the runners of the registry which are available on this system.
+func DefaultTasks() []Task {
+	var tasks []Task
+	for _, r := range registry {
+		// only keep runners which we have the proper dev tools installed for
+		if r.Available() {
+			tasks = append(tasks, r)
+		} else {
+			logf("skipping runner %s: its tools were not found in PATH", r.name)
+		}
+	}
+	return tasks
+}
+
+type runner struct {
+	name      string
+	patterns  []string // descriptive only, matches decides
+	available func() bool
+	matches   func(string) bool
+	// matchesDir matches directory names, optional - the path given to run is the matched directory then
+	matchesDir func(string) bool
+	run        func(string) error
+	verify     func(string) bool      // optional
+	reinstall  func(string) *exec.Cmd // optional
+	// artifacts are the directories removed by the runner, relative to the directory of the match
+	artifacts []string
+	// extraArtifacts returns the artifacts which are only removed with some options, optional
+	extraArtifacts func() []string
+	// keeps reports whether run keeps the existing artifact directory, optional - the walk descends into kept ones
+	keeps func(string) bool
+	// command is the external command run by the runner, if any
+	command string
+	// manifest is the file name of a minimal project for the self-test, if the runner removes directories only:
+	// the runner is expected to remove all artifact directories of a project with the given manifest file
+	manifest string
+}
+
+func (r runner) Available() bool {
+	return r.available()
+}
+func (r runner) Matches(name string) bool {
+	return r.matches(name)
+}
+func (r runner) MatchesDir(name string) bool {
+	if r.matchesDir == nil {
+		return false
+	}
+	return r.matchesDir(name)
+}
+func (r runner) Run(path string) error {
+	return r.run(path)
+}
+func (r runner) Reinstall(path string) *exec.Cmd {
+	if r.reinstall == nil {
+		return nil
+	}
+	return r.reinstall(path)
+}
+func (r runner) Name() string {
+	return r.name
+}
+func (r runner) Artifacts() []string {
+	if r.extraArtifacts == nil {
+		return r.artifacts
+	}
+	return append(append([]string{}, r.artifacts...), r.extraArtifacts()...)
+}
+func (r runner) Keeps(dir string) bool {
+	return r.keeps != nil && r.keeps(dir)
+}
+func (r runner) Command() string {
+	return r.command
+}
+func (r runner) Verify(path string) bool {
+	if r.verify == nil {
+		return true
+	}
+	return r.verify(path)
+}
+
+// RunnerNames returns the sorted names of all runners, whether they are available or not.
+func RunnerNames() []string {
+	names := make([]string, 0, len(registry))
+	for _, r := range registry {
+		names = append(names, r.name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,227 @@
+package purge
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReinstall(t *testing.T) {
+	tests := []struct {
+		runner string
+		files  []string
+		want   []string
+	}{
+		{"composer", []string{"composer.json"}, []string{"composer", "install", "--no-interaction"}},
+		{"pnpm", []string{"package.json"}, []string{"pnpm", "install"}},
+		{"pnpm", []string{"package.json", "pnpm-lock.yaml"}, []string{"pnpm", "install", "--frozen-lockfile"}},
+		{"yarn", []string{"package.json", "yarn.lock"}, []string{"yarn", "install"}},
+		{"npm", []string{"package.json"}, []string{"npm", "install"}},
+		{"npm", []string{"package.json", "package-lock.json"}, []string{"npm", "ci"}},
+		{"cargo", []string{"Cargo.toml"}, []string{appName("cargo"), "fetch"}},
+		{"pycache", []string{"__pycache__/"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.runner, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			cmd := testRunner(t, tt.runner).Reinstall(filepath.Join(dir, tt.files[0]))
+			if cmd == nil {
+				if tt.want != nil {
+					t.Fatalf("Reinstall() = nil, want %q", tt.want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(cmd.Args, tt.want) || cmd.Dir != dir {
+				t.Errorf("Reinstall() = %q in %s, want %q in %s", cmd.Args, cmd.Dir, tt.want, dir)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name      string
+		reinstall bool
+		dry       bool
+		want      []string
+	}{
+		{"disabled", false, false, nil},
+		{"dry run", true, true, nil},
+		{"reinstall", true, false, []string{"npm ci"}},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	stderr = ioutil.Discard
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{"npm": ""})
+			defer restore()
+			dir, cleanup := testTree(t, []string{"package.json", "package-lock.json"})
+			defer cleanup()
+			w := &walker{reinstall: tt.reinstall, dry: tt.dry}
+			if err := w.restore(testRunner(t, "npm"), filepath.Join(dir, "package.json")); err != nil {
+				t.Fatalf("restore() = %v", err)
+			}
+			if got := calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("restore() ran %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkaffold(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{"cache", []string{"skaffold.yaml", ".skaffold/cache", "k8s/deployment.yaml"}, []string{"k8s/", "k8s/deployment.yaml", "skaffold.yaml"}},
+		{"yml", []string{"skaffold.yml", ".skaffold/"}, []string{"skaffold.yml"}},
+		{"no cache", []string{"skaffold.yaml"}, []string{"skaffold.yaml"}},
+		{"nested cache", []string{"skaffold.yaml", "app/.skaffold/"}, []string{"app/", "app/.skaffold/", "skaffold.yaml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			if err := testRunner(t, "skaffold").run(filepath.Join(dir, tt.files[0])); err != nil {
+				t.Fatalf("run() = %v", err)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("run() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtraArgs(t *testing.T) {
+	tests := []struct {
+		runner   string
+		manifest string
+		options  runnerOptions
+		want     string
+	}{
+		{"cargo", "Cargo.toml", runnerOptions{cargoCleanMode: "full"}, "cargo clean"},
+		{"cargo", "Cargo.toml", runnerOptions{cargoCleanMode: "full", cargoArgs: []string{"--release"}}, "cargo clean --release"},
+		{"dotnet", "app.csproj", runnerOptions{}, "dotnet clean --nologo"},
+		{"dotnet", "app.csproj", runnerOptions{dotnetArgs: []string{"-c", "Release"}}, "dotnet clean --nologo -c Release"},
+	}
+	defer func(o runnerOptions) { options = o }(options)
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{tt.runner: ""})
+			defer restore()
+			dir, cleanup := testTree(t, []string{tt.manifest})
+			defer cleanup()
+			options = tt.options
+			options.root = dir
+			if err := testRunner(t, tt.runner).run(filepath.Join(dir, tt.manifest)); err != nil {
+				t.Fatalf("run() = %v", err)
+			}
+			if got := calls(); !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Errorf("run() ran %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDotnetFallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		// the fake dotnet clean leaves the build output alone
+		{"dotnet clean", "", []string{"App.csproj", "bin/", "bin/Debug/", "bin/Release/", "obj/", "obj/project.assets.json", "src/"}},
+		{"fallback", "echo 'unsupported project type' >&2; exit 1", []string{"App.csproj", "src/"}},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	stderr = ioutil.Discard
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{"dotnet": tt.script})
+			defer restore()
+			dir, cleanup := testTree(t, []string{"App.csproj", "bin/Debug/", "bin/Release/", "obj/project.assets.json", "src/"})
+			defer cleanup()
+			if err := testRunner(t, "dotnet").run(filepath.Join(dir, "App.csproj")); err != nil {
+				t.Fatalf("run() = %v", err)
+			}
+			if got := calls(); len(got) != 1 {
+				t.Errorf("run() ran %q, want dotnet clean", got)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("run() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCargoWorkspace(t *testing.T) {
+	workspace := "[workspace]\nmembers = [\"crates/*\"]\n"
+	tests := []struct {
+		name      string
+		manifests map[string]string
+		root      string // start of the walk, relative to the temporary directory
+		want      []string
+	}{
+		{
+			name:      "workspace",
+			manifests: map[string]string{"Cargo.toml": workspace, "crates/a/Cargo.toml": "", "crates/b/Cargo.toml": ""},
+			want:      []string{"."},
+		},
+		{
+			name:      "workspace with root package",
+			manifests: map[string]string{"Cargo.toml": "[package]\nname = \"app\"\n\n" + workspace, "crates/a/Cargo.toml": ""},
+			want:      []string{"."},
+		},
+		{
+			name:      "nested crates",
+			manifests: map[string]string{"Cargo.toml": "[package]\nname = \"app\"\n", "crates/a/Cargo.toml": ""},
+			want:      []string{".", "crates/a"},
+		},
+		{
+			name:      "member of a workspace outside of the root",
+			manifests: map[string]string{"Cargo.toml": workspace, "crates/a/Cargo.toml": "", "crates/b/Cargo.toml": ""},
+			root:      "crates",
+			want:      []string{"crates/a", "crates/b"},
+		},
+	}
+	defer func(o runnerOptions) { options = o }(options)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{"cargo": `echo "in $PWD" >> "${0%/*}/log"`})
+			defer restore()
+			var files []string
+			for name := range tt.manifests {
+				files = append(files, name)
+			}
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			// the working directory of the commands is resolved, e.g. on macOS
+			dir, err := filepath.EvalSymlinks(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, contents := range tt.manifests {
+				if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(contents), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			root := filepath.Join(dir, filepath.FromSlash(tt.root))
+			options = runnerOptions{root: root, cargoCleanMode: "full"}
+			w := &walker{tasks: []Task{testRunner(t, "cargo")}, root: root, out: ioutil.Discard, maxDepth: -1}
+			if err := w.walk(root, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			var want []string
+			for _, project := range tt.want {
+				want = append(want, "cargo clean", "in "+filepath.Join(dir, filepath.FromSlash(project)))
+			}
+			if got := calls(); !reflect.DeepEqual(got, want) {
+				t.Errorf("walk() ran %q, want %q", got, want)
+			}
+		})
+	}
+}
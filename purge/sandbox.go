@@ -0,0 +1,61 @@
+package purge
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// commandEnv is the environment of spawned clean and reinstall commands.
+// It is nil by default, so commands inherit the environment of this app.
+var commandEnv []string
+
+// sandboxVars lists the environment variables passed on to spawned commands in sandbox mode.
+// Everything else (e.g. LD_PRELOAD, NODE_OPTIONS or RUSTC_WRAPPER) may alter the behavior of the tools and is dropped.
+var sandboxVars = map[string]bool{
+	"HOME": true, "USER": true, "LOGNAME": true, "LANG": true, "TERM": true, "TZ": true,
+	"TMPDIR": true, "TMP": true, "TEMP": true,
+	// required by most programs on windows
+	"SYSTEMROOT": true, "SYSTEMDRIVE": true, "WINDIR": true, "COMSPEC": true, "PATHEXT": true,
+	"USERPROFILE": true, "APPDATA": true, "LOCALAPPDATA": true, "PROGRAMDATA": true,
+	// relocated homes of the supported package managers
+	"CARGO_HOME": true, "RUSTUP_HOME": true, "COMPOSER_HOME": true, "DOTNET_ROOT": true, "NUGET_PACKAGES": true,
+}
+
+// sandboxEnv returns a minimal copy of environ for spawned commands.
+// Only well-known variables are kept and PATH is reduced to absolute directories,
+// so a relative entry like `.` can't shadow the real tools with a script of the purged project.
+func sandboxEnv(environ []string) []string {
+	var env []string
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			// skips windows' hidden per-drive variables like `=C:=C:\`, too
+			continue
+		}
+		key, value := kv[:i], kv[i+1:]
+		if runtime.GOOS == "windows" {
+			// environment variables are case-insensitive on windows
+			key = strings.ToUpper(key)
+		}
+		switch {
+		case strings.HasPrefix(key, "LC_"), sandboxVars[key]:
+			env = append(env, kv)
+		case key == "PATH":
+			env = append(env, kv[:i+1]+sandboxPath(value))
+		}
+	}
+	return env
+}
+
+// sandboxPath removes all relative and empty entries from the search path.
+func sandboxPath(path string) string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(path) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return strings.Join(dirs, string(os.PathListSeparator))
+}
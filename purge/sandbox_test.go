@@ -0,0 +1,77 @@
+package purge
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSandboxEnv(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	abs, err := filepath.Abs("bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		environ []string
+		want    []string
+	}{
+		{"empty", nil, nil},
+		{"well-known", []string{"HOME=/home/gopher", "LANG=C.UTF-8", "LC_ALL=C", "CARGO_HOME=/opt/cargo"}, []string{"HOME=/home/gopher", "LANG=C.UTF-8", "LC_ALL=C", "CARGO_HOME=/opt/cargo"}},
+		{"dropped", []string{"LD_PRELOAD=/tmp/hook.so", "NODE_OPTIONS=--require=hook", "RUSTC_WRAPPER=sccache", "AWS_SECRET_ACCESS_KEY=secret"}, nil},
+		{"relative search path", []string{"PATH=." + sep + abs + sep + sep + "node_modules/.bin"}, []string{"PATH=" + abs}},
+		{"malformed", []string{"=C:=C:\\", "NOVALUE"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sandboxEnv(tt.environ); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sandboxEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSandboxCommand(t *testing.T) {
+	_, restore := testTools(t, map[string]string{"cargo": `/usr/bin/env > "${0%/*}/env"`})
+	defer restore()
+	tools := os.Getenv("PATH")
+	defer testSetenv(t, "PATH", "."+string(os.PathListSeparator)+tools)()
+	defer testSetenv(t, "NODE_OPTIONS", "--require=hook")()
+	defer testSetenv(t, "LC_ALL", "C")()
+	defer func(env []string) { commandEnv = env }(commandEnv)
+	commandEnv = sandboxEnv(os.Environ())
+	defer func(o runnerOptions) { options = o }(options)
+	dir, cleanup := testTree(t, []string{"Cargo.toml"})
+	defer cleanup()
+	options = runnerOptions{root: dir, cargoCleanMode: "full"}
+	if err := testRunner(t, "cargo").run(filepath.Join(dir, "Cargo.toml")); err != nil {
+		t.Fatalf("run() = %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(tools, "env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, kv := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// set by the shell itself
+		if !strings.HasPrefix(kv, "PWD=") && !strings.HasPrefix(kv, "SHLVL=") && !strings.HasPrefix(kv, "_=") && !strings.HasPrefix(kv, "OLDPWD=") {
+			got = append(got, kv)
+		}
+	}
+	sort.Strings(got)
+	want := sandboxEnv(os.Environ())
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("command received %q, want %q", got, want)
+	}
+	for _, kv := range got {
+		if strings.HasPrefix(kv, "NODE_OPTIONS=") || kv == "PATH=."+string(os.PathListSeparator)+tools {
+			t.Errorf("command received %q", kv)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package purge
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// selfTestContents holds the contents of fake manifests, which are inspected by a runner before it runs.
+// Manifests not listed here are created empty.
+var selfTestContents = map[string]string{
+	"composer.json":       "{\"require\": {\"php\": \">=7.2\"}}\n",
+	"requirements.yml":    "collections:\n  - name: community.general\n",
+	"manifest.json":       "{\"manifest_version\": 3, \"name\": \"example\", \"version\": \"1.0\"}\n",
+	"org.example.App.yml": "app-id: org.example.App\nruntime: org.freedesktop.Platform\nmodules: []\n",
+}
+
+// selfTestExtras lists additional empty files by runner name, which a runner requires to claim a project.
+var selfTestExtras = map[string][]string{
+	"pnpm":  {"pnpm-lock.yaml"},
+	"yarn":  {"yarn.lock"},
+	"cmake": {"build/CMakeCache.txt", "cmake-build-debug/CMakeCache.txt", "cmake-build-release/CMakeCache.txt"},
+	// only real virtual environments and the build output of setuptools are removed
+	"python": {".venv/pyvenv.cfg", "venv/pyvenv.cfg", "setup.py"},
+	// a module usually consists of several configuration files
+	"terraform": {"variables.tf", "outputs.tf", ".terraform.lock.hcl"},
+}
+
+// SelfTest purges a fake project of each available runner inside a temporary directory
+// and reports whether the runner removed all of its artifacts.
+// Runners which invoke external commands instead of removing directories are skipped.
+func SelfTest(out io.Writer) bool {
+	root, err := ioutil.TempDir("", "purge-npm-self-test")
+	if err != nil {
+		fmt.Fprintf(out, "failed to create temporary directory: %v\n", err)
+		return false
+	}
+	defer os.RemoveAll(root)
+	// the temporary directory may be behind a symbolic link, e.g. on macOS, which the walk doesn't leave
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	ok := true
+	for _, r := range registry {
+		switch {
+		case !r.Available():
+			fmt.Fprintf(out, "%-14s skipped (not available)\n", r.name)
+			continue
+		case r.manifest == "":
+			fmt.Fprintf(out, "%-14s skipped (runs external commands)\n", r.name)
+			continue
+		}
+		if err := selfTestRunner(filepath.Join(root, r.name), r); err != nil {
+			fmt.Fprintf(out, "%-14s FAIL: %v\n", r.name, err)
+			ok = false
+			continue
+		}
+		fmt.Fprintf(out, "%-14s ok\n", r.name)
+	}
+	return ok
+}
+
+// selfTestRunner creates a project with the manifest and artifacts of the runner in dir and purges it.
+func selfTestRunner(dir string, r runner) error {
+	for _, artifact := range r.artifacts {
+		if err := os.MkdirAll(filepath.Join(dir, artifact), 0755); err != nil {
+			return fmt.Errorf("failed to create artifact %s: %w", artifact, err)
+		}
+		// artifacts are never empty in real projects
+		if err := ioutil.WriteFile(filepath.Join(dir, artifact, "file"), []byte("x"), 0644); err != nil {
+			return fmt.Errorf("failed to create artifact %s: %w", artifact, err)
+		}
+	}
+	for _, name := range selfTestExtras[r.name] {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			return fmt.Errorf("failed to create file %s: %w", name, err)
+		}
+	}
+	manifest := filepath.Join(dir, r.manifest)
+	if err := ioutil.WriteFile(manifest, []byte(selfTestContents[r.manifest]), 0644); err != nil {
+		return fmt.Errorf("failed to create manifest %s: %w", r.manifest, err)
+	}
+
+	w := &walker{tasks: []Task{r}, root: dir, out: ioutil.Discard, maxDepth: -1}
+	if err := w.walk(dir, 0); err != nil {
+		return err
+	}
+	if len(w.failures) > 0 {
+		return w.failures[0]
+	}
+	for _, artifact := range r.artifacts {
+		if _, err := os.Stat(filepath.Join(dir, artifact)); err == nil {
+			return fmt.Errorf("artifact %s was not removed", artifact)
+		}
+	}
+	if _, err := os.Stat(manifest); err != nil {
+		return fmt.Errorf("manifest %s was removed", r.manifest)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package purge
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelfTestRunners(t *testing.T) {
+	defer func(out io.Writer) { stderr = out }(stderr)
+	stderr = ioutil.Discard
+	for _, r := range registry {
+		if r.manifest == "" {
+			// runs external commands
+			continue
+		}
+		r := r
+		t.Run(r.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, nil)
+			defer cleanup()
+			if err := selfTestRunner(filepath.Join(dir, r.name), r); err != nil {
+				t.Errorf("selfTestRunner() = %v", err)
+			}
+		})
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	// no tools are installed, only the runners without requirements are tested
+	_, restore := testTools(t, nil)
+	defer restore()
+	var out bytes.Buffer
+	if !SelfTest(&out) {
+		t.Errorf("SelfTest() failed:\n%s", out.String())
+	}
+	for _, want := range []string{"webext         ok\n", "npm            skipped (not available)\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("SelfTest() printed %q, want %q", out.String(), want)
+		}
+	}
+}
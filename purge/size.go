@@ -0,0 +1,77 @@
+package purge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// dirSize returns the accumulated size of all files below the given directory.
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := walkFS(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure size of directory %q: %w", path, err)
+	}
+	return size, nil
+}
+
+// reclaimableSize returns the size the task frees in the project directory dir: the size of its artifacts -
+// or of the whole project for tasks running an external command, which decides on its own what to remove.
+func reclaimableSize(dir string, task Task) (int64, error) {
+	if c, ok := task.(commander); ok && c.Command() != "" {
+		return dirSize(dir)
+	}
+	l, ok := task.(artifactLister)
+	if !ok {
+		return 0, nil
+	}
+	var total int64
+	for _, artifact := range l.Artifacts() {
+		path := filepath.Join(dir, filepath.FromSlash(artifact))
+		if _, err := fileSystem.Stat(path); errors.Is(err, os.ErrNotExist) || keepsArtifact(task, path) {
+			continue
+		}
+		size, err := dirSize(path)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// formatBytes formats a byte count as a human readable string with binary units, e.g. 1.5 GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// queryFreeSpace returns the free space of the filesystem containing path, it is replaced by tests.
+var queryFreeSpace = freeSpace
+
+// freeSpaceDelta describes the change of free space between two measurements next to the measured size of removed directories.
+// Both may differ, because of block sizes, hard links and concurrent writes to the filesystem.
+func freeSpaceDelta(before, after uint64, measured int64) string {
+	if after >= before {
+		return fmt.Sprintf("free space grew by %s (measured %s)", formatBytes(int64(after-before)), formatBytes(measured))
+	}
+	return fmt.Sprintf("free space shrank by %s (measured %s)", formatBytes(int64(before-after)), formatBytes(measured))
+}
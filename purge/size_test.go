@@ -0,0 +1,76 @@
+package purge
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFreeSpaceDelta(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after uint64
+		measured      int64
+		want          string
+	}{
+		{"grew", 1 << 30, 1<<30 + 3<<20, 3 << 20, "free space grew by 3.0 MiB (measured 3.0 MiB)"},
+		{"block sizes", 1 << 30, 1<<30 + 8192, 5000, "free space grew by 8.0 KiB (measured 4.9 KiB)"},
+		{"unchanged", 1 << 30, 1 << 30, 0, "free space grew by 0 B (measured 0 B)"},
+		{"concurrent writes", 1 << 30, 1<<30 - 1<<20, 2048, "free space shrank by 1.0 MiB (measured 2.0 KiB)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := freeSpaceDelta(tt.before, tt.after, tt.measured); got != tt.want {
+				t.Errorf("freeSpaceDelta() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunFreeSpace(t *testing.T) {
+	dir, cleanup := testTree(t, []string{"extension/web-ext-artifacts/app.js"})
+	defer cleanup()
+	manifest := `{"manifest_version": 2, "name": "test", "version": "1.0"}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "extension", "manifest.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "extension", "web-ext-artifacts", "app.js"), make([]byte, 1500), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the first query happens before the walk, the second one after it
+	defer func(f func(string) (uint64, error)) { queryFreeSpace = f }(queryFreeSpace)
+	free := []uint64{10 << 20, 10<<20 + 4096}
+	var queried []string
+	queryFreeSpace = func(path string) (uint64, error) {
+		queried = append(queried, path)
+		n := free[0]
+		free = free[1:]
+		return n, nil
+	}
+	var out bytes.Buffer
+	cfg := Config{
+		Roots:     []string{dir},
+		Tools:     []string{"webext"},
+		Stdout:    ioutil.Discard,
+		Stderr:    &out,
+		Depth:     -1,
+		SkipCache: true,
+		FreeSpace: true,
+	}
+	if err := Run(context.Background(), cfg); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	if want := "free space grew by 4.0 KiB (measured 1.5 KiB)\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("Run() printed %q, want %q", out.String(), want)
+	}
+	if len(queried) != 2 {
+		t.Errorf("Run() queried the free space %d times, want 2", len(queried))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "extension", "web-ext-artifacts")); !os.IsNotExist(err) {
+		t.Errorf("web-ext-artifacts was not removed")
+	}
+}
@@ -0,0 +1,101 @@
+package purge
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statusLine periodically reports the progress of a walk on stderr.
+// On a terminal the line is overwritten in place and cleared before any path is printed,
+// so it never ends up in the middle of the listing.
+type statusLine struct {
+	out     io.Writer
+	inPlace bool // overwrite the line with a carriage return instead of printing a new one
+	mu      sync.Mutex
+	width   int // length of the line currently on screen, guarded by mu
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// startStatus reports the counters of w every interval until the returned status line is stopped.
+func startStatus(w *walker, out io.Writer, interval time.Duration, inPlace bool) *statusLine {
+	s := &statusLine{out: out, inPlace: inPlace, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.print(w.progressLine())
+			}
+		}
+	}()
+	return s
+}
+
+// print shows line, replacing the previous one on a terminal.
+func (s *statusLine) print(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.inPlace {
+		fmt.Fprintln(s.out, line)
+		return
+	}
+	// pad with spaces, the previous line might have been longer - escape sequences aren't understood by every console
+	fmt.Fprintf(s.out, "\r%-*s", s.width, line)
+	s.width = len(line)
+}
+
+// clear removes the line from the screen and runs fn before it can be shown again.
+// A nil status line runs fn only.
+func (s *statusLine) clear(fn func()) {
+	if s == nil {
+		fn()
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.width > 0 {
+		fmt.Fprintf(s.out, "\r%s\r", strings.Repeat(" ", s.width))
+		s.width = 0
+	}
+	fn()
+}
+
+// close stops the reports and removes the last line from the screen.
+func (s *statusLine) close() {
+	close(s.stop)
+	<-s.done
+	s.clear(func() {})
+}
+
+// progressLine returns the current progress of the walk as a single line.
+// The size is only known if the matches are measured anyway, otherwise the number of projects is reported.
+func (w *walker) progressLine() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var projects int
+	var size int64
+	for _, t := range w.results {
+		projects += t.dirs
+		size += t.size
+	}
+	action := "cleaned"
+	if w.dry {
+		action = "found"
+	}
+	line := fmt.Sprintf("scanned %d %s, %s %d %s", w.visited, plural(w.visited, "directory", "directories"), action, projects, plural(projects, "project", "projects"))
+	if !w.measuring() {
+		return line
+	}
+	if w.dry {
+		return fmt.Sprintf("%s, %s reclaimable", line, formatBytes(size))
+	}
+	return fmt.Sprintf("%s, %s freed", line, formatBytes(size))
+}
@@ -0,0 +1,408 @@
+package purge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Task represents a runner which executes a function when a valid match is found.
+type Task interface {
+	Available() bool
+	Matches(string) bool
+	Run(string) error
+	Name() string // reports the task by, e.g. in the summary
+}
+
+// dirMatcher is implemented by tasks which match directories by their name instead of files, e.g. caches without a project file.
+// The task runs on the path of the matched directory.
+type dirMatcher interface {
+	MatchesDir(string) bool
+}
+
+// verifier is implemented by tasks which need to inspect the contents of a file with a matching name
+// to decide whether it really is a match.
+type verifier interface {
+	Verify(string) bool
+}
+
+// commander is implemented by tasks which run an external command instead of removing directories themselves.
+type commander interface {
+	Command() string
+}
+
+// artifactLister is implemented by tasks which know the directories they remove.
+type artifactLister interface {
+	Artifacts() []string
+}
+
+// artifactKeeper is implemented by tasks which keep some of their artifact directories,
+// e.g. a `build` directory which holds sources instead of build output.
+type artifactKeeper interface {
+	Keeps(string) bool
+}
+
+// keepsArtifact reports whether task keeps its existing artifact directory dir.
+func keepsArtifact(task Task, dir string) bool {
+	k, ok := task.(artifactKeeper)
+	return ok && k.Keeps(dir)
+}
+
+// reinstaller is implemented by tasks which are able to restore the dependencies they removed.
+type reinstaller interface {
+	Reinstall(string) *exec.Cmd
+}
+
+// cacheCleaner clears a global cache of a package manager or tool.
+type cacheCleaner struct {
+	name  string
+	clear func() error
+}
+
+// clearCachesGo clears the build, module and test caches of go.
+// The caches are independent, e.g. a read-only module cache doesn't keep the test cache from being cleared,
+// so all of them are cleared and the failures are reported together.
+func clearCachesGo() error {
+	var errs errorList
+	for _, cache := range []string{"-cache", "-modcache", "-testcache"} {
+		cmd := exec.Command(appName("go"), "clean", cache)
+		var out bytes.Buffer
+		cmd.Stderr = &out
+		if err := cmd.Run(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to run command %q: %w\n%s", cmd.String(), err, strings.TrimSpace(out.String())))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func clearCachesComposer() error {
+	cmd := exec.Command("composer", "--no-interaction", "clear-cache") // app will be found in PATH by `exec`
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	return nil
+}
+
+// cleanCargoTarget removes parts of the target directory of the cargo project in dir,
+// keeping the compiled dependencies:
+// mode `incremental` removes the incremental compilation caches of all profiles and targets,
+// mode `doc` removes the generated documentation.
+func cleanCargoTarget(dir, mode string) error {
+	var paths []string
+	switch mode {
+	case "incremental":
+		// target/<profile>/incremental and target/<triple>/<profile>/incremental
+		for _, pattern := range []string{
+			filepath.Join(dir, "target", "*", "incremental"),
+			filepath.Join(dir, "target", "*", "*", "incremental"),
+		} {
+			matches, err := globFS(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to search incremental caches in %s: %w", dir, err)
+			}
+			paths = append(paths, matches...)
+		}
+	case "doc":
+		paths = append(paths, filepath.Join(dir, "target", "doc"))
+	}
+	for _, path := range paths {
+		if err := removeAll(path); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// reCargoWorkspace matches the workspace table of a Cargo.toml
+var reCargoWorkspace = regexp.MustCompile(`(?m)^\s*\[workspace\]`)
+
+// isCargoWorkspaceMember reports whether the Cargo.toml at path belongs to a crate
+// within a cargo workspace, whose root is inside of root.
+func isCargoWorkspaceMember(path, root string) bool {
+	for dir := filepath.Dir(path); dir != root && dir != filepath.Dir(dir); {
+		dir = filepath.Dir(dir)
+		if rel, err := filepath.Rel(root, dir); err != nil || strings.HasPrefix(rel, "..") {
+			return false
+		}
+		if manifest, err := fileSystem.ReadFile(filepath.Join(dir, "Cargo.toml")); err == nil && reCargoWorkspace.Match(manifest) {
+			return true
+		}
+	}
+	return false
+}
+
+// cargoHome returns cargo's home directory, which may be overridden by `CARGO_HOME`.
+func cargoHome() (string, error) {
+	if dir := os.Getenv("CARGO_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".cargo"), nil
+}
+
+// clearCachesCargoRegistry removes the downloaded crate archives and their extracted sources.
+// Fetching the registry index again is slow, so it is only removed with deep set.
+func clearCachesCargoRegistry(deep bool) error {
+	home, err := cargoHome()
+	if err != nil {
+		return err
+	}
+	dirs := []string{"cache", "src"}
+	if deep {
+		dirs = append(dirs, "index")
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(home, "registry", dir)
+		if err := removeAll(path); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// isComposerProject reports whether the composer.json at path belongs to a project with dependencies.
+// Some repositories ship a composer.json for tooling only, their vendor directory may belong to something else.
+func isComposerProject(path string) bool {
+	if _, err := fileSystem.Stat(filepath.Join(filepath.Dir(path), "composer.lock")); err == nil {
+		return true
+	}
+	var manifest struct {
+		Require    map[string]json.RawMessage `json:"require"`
+		RequireDev map[string]json.RawMessage `json:"require-dev"`
+	}
+	if data, err := fileSystem.ReadFile(path); err == nil && json.Unmarshal(data, &manifest) == nil {
+		if len(manifest.Require) > 0 || len(manifest.RequireDev) > 0 {
+			return true
+		}
+	}
+	// verified for each walk, preview and count, so it is a detail
+	logf("skipping %s: declares no dependencies and has no composer.lock", path)
+	return false
+}
+
+// composerHome returns Composer's home directory, which may be overridden by `COMPOSER_HOME`.
+func composerHome() (string, error) {
+	if dir := os.Getenv("COMPOSER_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	legacy := filepath.Join(home, ".composer")
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy, nil
+	}
+	// newer composer versions follow the platform conventions when ~/.composer doesn't exist
+	if config, err := os.UserConfigDir(); err == nil {
+		dir := filepath.Join(config, "composer")
+		if _, err := os.Stat(dir); err == nil {
+			return dir, nil
+		}
+	}
+	return legacy, nil
+}
+
+// purgeComposerGlobal reports the size of and/or removes the vendor directory
+// of globally required Composer packages.
+// Global packages are mostly installed CLI tools, so removal is strictly opt-in.
+func (w *walker) purgeComposerGlobal(report, remove bool) error {
+	home, err := composerHome()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(home, "vendor")
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		if report {
+			fmt.Fprintf(stderr, "composer global vendor directory %s does not exist\n", dir)
+		}
+		return nil
+	}
+	if report {
+		size, err := dirSize(dir)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "composer global vendor directory %s uses %s\n", dir, formatBytes(size))
+	}
+	if remove {
+		if err := w.record(dir, "composer-global"); err != nil {
+			return err
+		}
+		if w.dry {
+			return nil
+		}
+		if err := removeAll(dir); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+func clearCachesNpm() error {
+	cmd := exec.Command("npm", "cache", "clean", "--force")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	return nil
+}
+
+// clearCachesHelm removes helm's repository cache, which may be relocated by `HELM_CACHE_HOME`.
+func clearCachesHelm() error {
+	dir := os.Getenv("HELM_CACHE_HOME")
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return fmt.Errorf("failed to find cache directory: %w", err)
+		}
+		dir = filepath.Join(cache, "helm")
+	}
+	if err := removeAll(dir); err != nil {
+		return fmt.Errorf("failed to remove path %s: %w", dir, err)
+	}
+	return nil
+}
+
+func clearCachesHomebrew() error {
+	cmd := exec.Command("brew", "cleanup", "-s")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	return nil
+}
+
+func clearCachesApt() error {
+	cmd := exec.Command("apt-get", "clean")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	return nil
+}
+
+// geteuid returns the effective user id, it is replaced by tests.
+var geteuid = os.Geteuid
+
+// clearCachesSystem clears the download caches of installed system package managers.
+// apt requires root privileges, so its cache is left alone for regular users.
+func clearCachesSystem() error {
+	if _, err := exec.LookPath("brew"); err == nil {
+		if err := clearCachesHomebrew(); err != nil {
+			return err
+		}
+	}
+	if _, err := exec.LookPath("apt-get"); err == nil {
+		if geteuid() != 0 {
+			fmt.Fprintln(stderr, "skipping apt cache: clearing it requires root privileges")
+			return nil
+		}
+		if err := clearCachesApt(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func clearCachesYarn() error {
+	cmd := exec.Command("yarn", "cache", "clean")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	return nil
+}
+
+func clearCachesPnpm() error {
+	cmd := exec.Command("pnpm", "store", "prune")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run command %q: %w", cmd.String(), err)
+	}
+	return nil
+}
+
+// reTempInstaller matches the names of the temporary directories created by npm and yarn during installs:
+// npm names them after its process id and 8 random hex digits, yarn after the time in milliseconds and a random fraction.
+// Other tools use the same prefixes, e.g. npm-debug or yarn-cache, so nothing else is matched.
+var reTempInstaller = regexp.MustCompile(`^(npm-[0-9]+-[0-9a-f]{8}|yarn--[0-9]+-0\.[0-9]+)$`)
+
+// tempInstallerMaxAge is the age of temporary install directories after which they're considered orphaned.
+const tempInstallerMaxAge = 24 * time.Hour
+
+// clearCachesTempInstallers removes orphaned temporary directories of interrupted npm and yarn installs
+// from the temporary directory. Recent ones may belong to a running install, so they are left alone.
+func clearCachesTempInstallers() error {
+	dir := os.TempDir()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read file entries of directory %q: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !reTempInstaller.MatchString(entry.Name()) || time.Since(entry.ModTime()) < tempInstallerMaxAge {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := removeAll(path); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// isNodeProjectOf reports whether the node project of the package.json at path belongs to the package manager
+// with the given lock file: either the lock file exists, or npm - the fallback for all other projects - is missing.
+func isNodeProjectOf(path, lockFile string) bool {
+	if _, err := fileSystem.Stat(filepath.Join(filepath.Dir(path), lockFile)); err == nil {
+		return true
+	}
+	_, err := exec.LookPath("npm")
+	return err != nil
+}
+
+// clearCachesJS clears the global caches and stores of all installed javascript package managers.
+// A failing package manager doesn't keep the others from clearing their caches, the failures are reported together.
+func clearCachesJS() error {
+	var errs errorList
+	cleaners := []struct {
+		name  string
+		clear func() error
+	}{
+		{name: "npm", clear: clearCachesNpm},
+		{name: "yarn", clear: clearCachesYarn},
+		{name: "pnpm", clear: clearCachesPnpm},
+	}
+	for _, c := range cleaners {
+		// the node tools are scripts (e.g. npm.cmd on windows), so `exec` has to find the proper extension
+		if _, err := exec.LookPath(c.name); err != nil {
+			continue
+		}
+		if err := c.clear(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(stderr, "cleared %s cache\n", c.name)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func appName(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
@@ -0,0 +1,465 @@
+package purge
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsComposerProject(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		lock     bool
+		want     bool
+	}{
+		{"dependencies", `{"require": {"php": ">=7.4"}}`, false, true},
+		{"dev dependencies", `{"require-dev": {"phpunit/phpunit": "^9"}}`, false, true},
+		{"lock file", `{}`, true, true},
+		{"tooling only", `{"scripts": {"lint": "phpcs"}}`, false, false},
+		{"invalid", `{`, false, false},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := []string{"composer.json"}
+			if tt.lock {
+				files = append(files, "composer.lock")
+			}
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			path := filepath.Join(dir, "composer.json")
+			if err := ioutil.WriteFile(path, []byte(tt.manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			stderr = &out
+			if got := isComposerProject(path); got != tt.want {
+				t.Errorf("isComposerProject() = %v, want %v", got, tt.want)
+			}
+			// skipped projects are logged with -verbose only
+			if out.Len() > 0 {
+				t.Errorf("isComposerProject() printed %q", out.String())
+			}
+		})
+	}
+}
+
+func TestComposerHome(t *testing.T) {
+	defer testSetenv(t, "COMPOSER_HOME", "/opt/composer")()
+	if got, err := composerHome(); err != nil || got != "/opt/composer" {
+		t.Errorf("composerHome() = %q, %v, want %q", got, err, "/opt/composer")
+	}
+}
+
+func TestPurgeComposerGlobal(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  bool
+		remove  bool
+		dry     bool
+		removed bool
+		printed string
+	}{
+		{name: "report", report: true, printed: "uses 3 B"},
+		{name: "remove", remove: true, removed: true},
+		{name: "report and remove", report: true, remove: true, removed: true, printed: "uses 3 B"},
+		{name: "dry run", remove: true, dry: true},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, cleanup := testTree(t, []string{"vendor/bin/"})
+			defer cleanup()
+			if err := ioutil.WriteFile(filepath.Join(home, "vendor", "autoload.php"), []byte("php"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			defer testSetenv(t, "COMPOSER_HOME", home)()
+			var errOut, out bytes.Buffer
+			stderr = &errOut
+			w := &walker{out: &out, dry: tt.dry}
+			if err := w.purgeComposerGlobal(tt.report, tt.remove); err != nil {
+				t.Fatalf("purgeComposerGlobal() = %v", err)
+			}
+			vendor := filepath.Join(home, "vendor")
+			if _, err := os.Stat(vendor); os.IsNotExist(err) != tt.removed {
+				t.Errorf("vendor removed = %v, want %v", os.IsNotExist(err), tt.removed)
+			}
+			if !strings.Contains(errOut.String(), tt.printed) || tt.printed == "" && errOut.Len() > 0 {
+				t.Errorf("purgeComposerGlobal() reported %q, want %q", errOut.String(), tt.printed)
+			}
+			// the removed directory is listed like a processed project
+			if tt.remove && out.String() != vendor+"\n" {
+				t.Errorf("purgeComposerGlobal() printed %q, want %q", out.String(), vendor+"\n")
+			}
+		})
+	}
+}
+
+func TestClearCachesJS(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []string
+		failing   []string // installed tools which fail to clear their cache
+		want      []string
+	}{
+		{"none", nil, nil, nil},
+		{"npm only", []string{"npm"}, nil, []string{"npm cache clean --force"}},
+		{"npm and pnpm", []string{"npm", "pnpm"}, nil, []string{"npm cache clean --force", "pnpm store prune"}},
+		{"all", []string{"npm", "yarn", "pnpm"}, nil, []string{"npm cache clean --force", "yarn cache clean", "pnpm store prune"}},
+		{"failing yarn", []string{"npm", "yarn", "pnpm"}, []string{"yarn"}, []string{"npm cache clean --force", "yarn cache clean", "pnpm store prune"}},
+		{"all failing", []string{"npm", "yarn", "pnpm"}, []string{"npm", "yarn", "pnpm"}, []string{"npm cache clean --force", "yarn cache clean", "pnpm store prune"}},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	stderr = ioutil.Discard
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tools := map[string]string{}
+			for _, name := range tt.installed {
+				tools[name] = ""
+			}
+			for _, name := range tt.failing {
+				tools[name] = "exit 1"
+			}
+			calls, cleanup := testTools(t, tools)
+			defer cleanup()
+			err := clearCachesJS()
+			// all installed package managers run, regardless of earlier failures
+			if got := calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clearCachesJS() ran %q, want %q", got, tt.want)
+			}
+			if tt.failing == nil {
+				if err != nil {
+					t.Fatalf("clearCachesJS() = %v", err)
+				}
+				return
+			}
+			errs, ok := err.(errorList)
+			if !ok || len(errs) != len(tt.failing) {
+				t.Fatalf("clearCachesJS() = %v, want %d errors", err, len(tt.failing))
+			}
+			for i, name := range tt.failing {
+				if !strings.Contains(errs[i].Error(), name+" ") {
+					t.Errorf("clearCachesJS() reported %q for %s", errs[i], name)
+				}
+			}
+		})
+	}
+}
+
+func TestClearCachesGo(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		failed []string // subcommands reported as failed
+	}{
+		{"success", "", nil},
+		{"read-only module cache", `[ "$2" = -modcache ] && echo "read-only file system" >&2 && exit 1; exit 0`, []string{"-modcache"}},
+		{"everything fails", `echo "read-only file system" >&2; exit 1`, []string{"-cache", "-modcache", "-testcache"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{"go": tt.script})
+			defer restore()
+			err := clearCachesGo()
+			// all subcommands run, regardless of earlier failures
+			if got, want := calls(), []string{"go clean -cache", "go clean -modcache", "go clean -testcache"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("clearCachesGo() ran %q, want %q", got, want)
+			}
+			if tt.failed == nil {
+				if err != nil {
+					t.Fatalf("clearCachesGo() = %v", err)
+				}
+				return
+			}
+			errs, ok := err.(errorList)
+			if !ok || len(errs) != len(tt.failed) {
+				t.Fatalf("clearCachesGo() = %v, want %d errors", err, len(tt.failed))
+			}
+			for i, cache := range tt.failed {
+				if msg := errs[i].Error(); !strings.Contains(msg, "clean "+cache) || !strings.Contains(msg, "read-only file system") {
+					t.Errorf("clearCachesGo() reported %q for %s", msg, cache)
+				}
+			}
+		})
+	}
+}
+
+func TestClearCachesHelm(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string // HELM_CACHE_HOME below the temporary directory
+		want []string
+	}{
+		{"default", "", []string{"cache/", "custom/", "custom/repository/"}},
+		{"HELM_CACHE_HOME", "custom", []string{"cache/", "cache/helm/", "cache/helm/repository/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"cache/helm/repository/", "custom/repository/"})
+			defer cleanup()
+			// os.UserCacheDir uses XDG_CACHE_HOME on Linux and BSD only
+			if runtime.GOOS == "windows" || runtime.GOOS == "darwin" || runtime.GOOS == "plan9" {
+				t.Skip("cache directory is not configurable")
+			}
+			defer testSetenv(t, "XDG_CACHE_HOME", filepath.Join(dir, "cache"))()
+			env := ""
+			if tt.env != "" {
+				env = filepath.Join(dir, tt.env)
+			}
+			defer testSetenv(t, "HELM_CACHE_HOME", env)()
+			if err := clearCachesHelm(); err != nil {
+				t.Fatalf("clearCachesHelm() = %v", err)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clearCachesHelm() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanCargoTarget(t *testing.T) {
+	files := []string{
+		"Cargo.toml",
+		"target/debug/deps/libserde.rlib",
+		"target/debug/incremental/app-1/dep-graph.bin",
+		"target/doc/app/index.html",
+		"target/release/incremental/app-2/dep-graph.bin",
+		"target/x86_64-unknown-linux-musl/release/incremental/app-3/dep-graph.bin",
+	}
+	tests := []struct {
+		mode  string
+		calls []string
+		want  []string
+	}{
+		{"full", []string{"cargo clean"}, files},
+		{"incremental", nil, []string{
+			"Cargo.toml",
+			"target/debug/deps/libserde.rlib",
+			"target/doc/app/index.html",
+		}},
+		{"doc", nil, []string{
+			"Cargo.toml",
+			"target/debug/deps/libserde.rlib",
+			"target/debug/incremental/app-1/dep-graph.bin",
+			"target/release/incremental/app-2/dep-graph.bin",
+			"target/x86_64-unknown-linux-musl/release/incremental/app-3/dep-graph.bin",
+		}},
+	}
+	defer func(o runnerOptions) { options = o }(options)
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{"cargo": ""})
+			defer restore()
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			options = runnerOptions{root: dir, cargoCleanMode: tt.mode}
+			if err := testRunner(t, "cargo").run(filepath.Join(dir, "Cargo.toml")); err != nil {
+				t.Fatalf("run() = %v", err)
+			}
+			if got := calls(); !reflect.DeepEqual(got, tt.calls) {
+				t.Errorf("run() ran %q, want %q", got, tt.calls)
+			}
+			// compare the files only, the parents of removed directories are kept
+			var got []string
+			for _, name := range testFiles(t, dir) {
+				if !strings.HasSuffix(name, "/") {
+					got = append(got, name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("run() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClearCachesSystem(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []string
+		uid       int
+		want      []string
+		printed   string
+	}{
+		{"none", nil, 1000, nil, ""},
+		{"brew", []string{"brew"}, 1000, []string{"brew cleanup -s"}, ""},
+		{"apt as user", []string{"apt-get"}, 1000, nil, "skipping apt cache: clearing it requires root privileges\n"},
+		{"apt as root", []string{"apt-get"}, 0, []string{"apt-get clean"}, ""},
+		{"both", []string{"brew", "apt-get"}, 0, []string{"brew cleanup -s", "apt-get clean"}, ""},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	defer func(f func() int) { geteuid = f }(geteuid)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tools := map[string]string{}
+			for _, name := range tt.installed {
+				tools[name] = ""
+			}
+			calls, cleanup := testTools(t, tools)
+			defer cleanup()
+			geteuid = func() int { return tt.uid }
+			var out bytes.Buffer
+			stderr = &out
+			if err := clearCachesSystem(); err != nil {
+				t.Fatalf("clearCachesSystem() = %v", err)
+			}
+			if got := calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clearCachesSystem() ran %q, want %q", got, tt.want)
+			}
+			if out.String() != tt.printed {
+				t.Errorf("clearCachesSystem() printed %q, want %q", out.String(), tt.printed)
+			}
+		})
+	}
+}
+
+func TestClearCachesCargoRegistry(t *testing.T) {
+	tests := []struct {
+		name      string
+		cargoHome string // CARGO_HOME below the temporary directory
+		deep      bool
+		want      []string
+	}{
+		{"default", "cargo", false, []string{"cargo/bin/", "cargo/registry/index/", "home/.cargo/registry/cache/", "home/.cargo/registry/index/"}},
+		{"deep", "cargo", true, []string{"cargo/bin/", "home/.cargo/registry/cache/", "home/.cargo/registry/index/"}},
+		{"home directory", "", false, []string{"cargo/bin/", "cargo/registry/cache/", "cargo/registry/src/", "cargo/registry/index/", "home/.cargo/registry/index/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := []string{
+				"cargo/bin/", "cargo/registry/cache/", "cargo/registry/src/", "cargo/registry/index/",
+				"home/.cargo/registry/cache/", "home/.cargo/registry/index/",
+			}
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			cargoHome := ""
+			if tt.cargoHome != "" {
+				cargoHome = filepath.Join(dir, tt.cargoHome)
+			}
+			defer testSetenv(t, "CARGO_HOME", cargoHome)()
+			defer testSetenv(t, "HOME", filepath.Join(dir, "home"))()
+			defer testSetenv(t, "USERPROFILE", filepath.Join(dir, "home"))()
+			if err := clearCachesCargoRegistry(tt.deep); err != nil {
+				t.Fatalf("clearCachesCargoRegistry() = %v", err)
+			}
+			var got []string
+			for _, name := range files {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+					got = append(got, name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clearCachesCargoRegistry() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurgeCachesStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		composer string // script of the fake composer
+		strict   bool
+		wantErr  bool
+		printed  string
+	}{
+		{"success", "", false, false, ""},
+		{"failure", "exit 1", false, false, "purging 1 of 4 global caches failed (use -strict-global to treat this as an error)\n"},
+		{"strict success", "", true, false, ""},
+		{"strict failure", "exit 1", true, true, ""},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, restore := testTools(t, map[string]string{"go": "", "npm": "", "composer": tt.composer})
+			defer restore()
+			// leftovers of interrupted installs are removed from the temporary directory
+			tmp, cleanup := testTree(t, nil)
+			defer cleanup()
+			defer testSetenv(t, "TMPDIR", tmp)()
+			var out bytes.Buffer
+			stderr = &out
+			err := purgeCaches(Config{StrictGlobal: tt.strict})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("purgeCaches() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "composer cache") {
+				t.Errorf("purgeCaches() = %v", err)
+			}
+			// the failure itself is printed before the count
+			if !strings.HasSuffix(out.String(), tt.printed) || tt.printed == "" && out.Len() > 0 {
+				t.Errorf("purgeCaches() printed %q, want %q", out.String(), tt.printed)
+			}
+		})
+	}
+}
+
+func TestPurgeCachesJS(t *testing.T) {
+	tests := []struct {
+		name        string
+		jsGlobalAll bool
+		want        []string
+	}{
+		{"default", false, []string{"npm cache clean --force"}},
+		{"js-global-all", true, []string{"npm cache clean --force", "yarn cache clean", "pnpm store prune"}},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	stderr = ioutil.Discard
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := testTools(t, map[string]string{"go": "", "composer": "", "npm": "", "yarn": "", "pnpm": ""})
+			defer restore()
+			tmp, cleanup := testTree(t, nil)
+			defer cleanup()
+			defer testSetenv(t, "TMPDIR", tmp)()
+			if err := purgeCaches(Config{JSGlobalAll: tt.jsGlobalAll}); err != nil {
+				t.Fatalf("purgeCaches() = %v", err)
+			}
+			var got []string
+			for _, call := range calls() {
+				if strings.HasPrefix(call, "npm ") || strings.HasPrefix(call, "yarn ") || strings.HasPrefix(call, "pnpm ") {
+					got = append(got, call)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("purgeCaches() ran %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClearCachesTempInstallers(t *testing.T) {
+	stale := []string{
+		"npm-1234-0a1b2c3d", "yarn--1600000000000-0.8351289736", "yarn-cache", "go-build123", "npm-debug.log",
+		"npm-1234-project", "npm-cache-0a1b2c3d", "yarn--dev",
+	}
+	dir, cleanup := testTree(t, []string{
+		"npm-1234-0a1b2c3d/", "npm-5678-4e5f6a7b/", "yarn--1600000000000-0.8351289736/", "yarn-cache/", "go-build123/", "npm-debug.log",
+		"npm-1234-project/", "npm-cache-0a1b2c3d/", "yarn--dev/",
+	})
+	defer cleanup()
+	old := time.Now().Add(-2 * tempInstallerMaxAge)
+	for _, name := range stale {
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer testSetenv(t, "TMPDIR", dir)()
+	defer testSetenv(t, "TMP", dir)()
+	if err := clearCachesTempInstallers(); err != nil {
+		t.Fatalf("clearCachesTempInstallers() = %v", err)
+	}
+	// recent and similarly named directories of other tools are kept
+	want := []string{"go-build123/", "npm-1234-project/", "npm-5678-4e5f6a7b/", "npm-cache-0a1b2c3d/", "npm-debug.log", "yarn--dev/", "yarn-cache/"}
+	if got := testFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("clearCachesTempInstallers() kept %q, want %q", got, want)
+	}
+}
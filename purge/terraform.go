@@ -0,0 +1,30 @@
+package purge
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// isTerraformName reports whether the file holds terraform configuration.
+// A module usually consists of several of them, but the walk runs a task once per directory only.
+func isTerraformName(name string) bool {
+	return strings.HasSuffix(name, ".tf") && name != ".tf"
+}
+
+// removeTerraform removes the provider plugins and modules downloaded by `terraform init`
+// and the dependency lock file next to the configuration file at path.
+// Terraform recommends to commit the lock file, which is kept then, as it pins the provider versions.
+func removeTerraform(path string) error {
+	for _, name := range []string{".terraform", ".terraform.lock.hcl"} {
+		p := filepath.Join(filepath.Dir(path), name)
+		if name == ".terraform.lock.hcl" && isGitTracked(p) {
+			logf("skipping %s: committed to git", p)
+			continue
+		}
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
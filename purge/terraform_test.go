@@ -0,0 +1,49 @@
+package purge
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveTerraform(t *testing.T) {
+	if _, err := exec.LookPath(appName("git")); err != nil {
+		t.Skip("git is not installed")
+	}
+	tests := []struct {
+		name    string
+		git     bool
+		tracked []string
+		want    bool // whether the lock file is kept
+	}{
+		{"no repository", false, nil, false},
+		{"untracked lock file", true, []string{"main.tf"}, false},
+		{"committed lock file", true, []string{"main.tf", ".terraform.lock.hcl"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"main.tf", ".terraform.lock.hcl", ".terraform/providers/"})
+			defer cleanup()
+			if tt.git {
+				args := [][]string{{"init", "-q"}, append([]string{"add", "--"}, tt.tracked...)}
+				for _, a := range args {
+					cmd := exec.Command(appName("git"), a...)
+					cmd.Dir = dir
+					if out, err := cmd.CombinedOutput(); err != nil {
+						t.Fatalf("git %v: %v\n%s", a, err, out)
+					}
+				}
+			}
+			if err := removeTerraform(filepath.Join(dir, "main.tf")); err != nil {
+				t.Fatalf("removeTerraform() = %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, ".terraform")); !os.IsNotExist(err) {
+				t.Errorf(".terraform was not removed")
+			}
+			if _, err := os.Stat(filepath.Join(dir, ".terraform.lock.hcl")); (err == nil) != tt.want {
+				t.Errorf("lock file kept = %v, want %v", err == nil, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// commandTimeout limits the run time of each spawned clean command, 0 means unlimited.
+// Reinstalls download whole dependency trees, so they are never limited.
+var commandTimeout time.Duration
+
+// errCommandTimeout marks clean commands which were killed after commandTimeout.
+var errCommandTimeout = errors.New("command timed out")
+
+// commandContext returns the context of a single clean command, which ends after commandTimeout.
+func commandContext() (context.Context, context.CancelFunc) {
+	if commandTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), commandTimeout)
+}
+
+// commandError returns errCommandTimeout, if the context of the failed command ended before the command did, and err otherwise.
+func commandError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v", errCommandTimeout, commandTimeout)
+	}
+	return err
+}
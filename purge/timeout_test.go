@@ -0,0 +1,56 @@
+package purge
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		runner   string
+		manifest string
+		wantErr  bool
+		logged   string
+		kept     []string
+	}{
+		{"strict", "cargo", "Cargo.toml", true, "", []string{"Cargo.toml", "bin/", "obj/"}},
+		{"tolerant", "dotnet", "App.csproj", false, "command timed out after 50ms", []string{"App.csproj"}},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	defer func(d time.Duration) { commandTimeout = d }(commandTimeout)
+	defer func(o runnerOptions) { options = o }(options)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// exec replaces the shell, so killing the fake tool doesn't leave an orphan holding its output open
+			_, restore := testTools(t, map[string]string{tt.runner: "exec /bin/sleep 10"})
+			defer restore()
+			dir, cleanup := testTree(t, []string{tt.manifest, "bin/", "obj/"})
+			defer cleanup()
+			var logged bytes.Buffer
+			stderr = &logged
+			commandTimeout = 50 * time.Millisecond
+			options = runnerOptions{root: dir, cargoCleanMode: "full"}
+			start := time.Now()
+			err := testRunner(t, tt.runner).run(filepath.Join(dir, tt.manifest))
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("run() returned after %v", elapsed)
+			}
+			if (err != nil) != tt.wantErr || err != nil && !errors.Is(err, errCommandTimeout) {
+				t.Fatalf("run() = %v, want timeout %v", err, tt.wantErr)
+			}
+			if !strings.Contains(logged.String(), tt.logged) {
+				t.Errorf("run() logged %q, want %q", logged.String(), tt.logged)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.kept) {
+				t.Errorf("run() kept %q, want %q", got, tt.kept)
+			}
+		})
+	}
+}
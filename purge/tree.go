@@ -0,0 +1,98 @@
+package purge
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sizeTree aggregates the sizes of removable directories up the directory hierarchy below root.
+type sizeTree struct {
+	root     string
+	sizes    map[string]int64    // accumulated size by directory
+	children map[string][]string // child directories by parent directory
+}
+
+func newSizeTree(root string) *sizeTree {
+	return &sizeTree{
+		root:     root,
+		sizes:    map[string]int64{root: 0},
+		children: map[string][]string{},
+	}
+}
+
+// add records the size of dir and adds it to the size of each parent directory up to the root.
+func (t *sizeTree) add(dir string, size int64) {
+	for {
+		if _, ok := t.sizes[dir]; !ok && dir != t.root {
+			parent := filepath.Dir(dir)
+			t.children[parent] = append(t.children[parent], dir)
+		}
+		t.sizes[dir] += size
+		if dir == t.root || dir == filepath.Dir(dir) {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// print writes the tree in the style of `du`, the largest directories first.
+func (t *sizeTree) print(out io.Writer) {
+	t.printDir(out, t.root, 0)
+}
+
+func (t *sizeTree) printDir(out io.Writer, dir string, depth int) {
+	name := dir
+	if depth > 0 {
+		name = filepath.Base(dir)
+	}
+	fmt.Fprintf(out, "%10s  %s%s\n", formatBytes(t.sizes[dir]), strings.Repeat("  ", depth), name)
+	children := t.children[dir]
+	sort.Slice(children, func(i, j int) bool {
+		if t.sizes[children[i]] != t.sizes[children[j]] {
+			return t.sizes[children[i]] > t.sizes[children[j]]
+		}
+		return children[i] < children[j]
+	})
+	for _, child := range children {
+		t.printDir(out, child, depth+1)
+	}
+}
+
+// measure adds the size of each existing artifact directory of the project in dir
+// to the measured total and the requested reports and returns the size of all of them.
+func (w *walker) measure(dir string, task Task, modTime time.Time) (int64, error) {
+	l, ok := task.(artifactLister)
+	if !ok {
+		return 0, nil
+	}
+	name := task.Name()
+	var total int64
+	for _, artifact := range l.Artifacts() {
+		path := filepath.Join(dir, artifact)
+		if _, err := fileSystem.Stat(path); errors.Is(err, os.ErrNotExist) || keepsArtifact(task, path) {
+			continue
+		}
+		size, err := dirSize(path)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+		w.measured += size
+		if w.byTool != nil {
+			w.byTool.add(name, size)
+		}
+		if w.tree != nil {
+			w.tree.add(path, size)
+		}
+		if w.ages != nil {
+			w.ages.add(time.Since(modTime), size)
+		}
+	}
+	return total, nil
+}
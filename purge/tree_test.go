@@ -0,0 +1,60 @@
+package purge
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestSizeTree(t *testing.T) {
+	root := filepath.FromSlash("/code")
+	tests := []struct {
+		name  string
+		sizes map[string]int64
+		want  string
+	}{
+		{"empty", nil, "       0 B  /code\n"},
+		{
+			name:  "single",
+			sizes: map[string]int64{"web/node_modules": 2048},
+			want: "   2.0 KiB  /code\n" +
+				"   2.0 KiB    web\n" +
+				"   2.0 KiB      node_modules\n",
+		},
+		{
+			name: "largest first",
+			sizes: map[string]int64{
+				"a/node_modules":            100,
+				"b/node_modules":            300,
+				"b/packages/x/node_modules": 200,
+				"c/target":                  100,
+				"b/packages/y/node_modules": 0,
+			},
+			want: "     700 B  /code\n" +
+				"     500 B    b\n" +
+				"     300 B      node_modules\n" +
+				"     200 B      packages\n" +
+				"     200 B        x\n" +
+				"     200 B          node_modules\n" +
+				"       0 B        y\n" +
+				"       0 B          node_modules\n" +
+				"     100 B    a\n" +
+				"     100 B      node_modules\n" +
+				"     100 B    c\n" +
+				"     100 B      target\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := newSizeTree(root)
+			for dir, size := range tt.sizes {
+				tree.add(filepath.Join(root, filepath.FromSlash(dir)), size)
+			}
+			var out bytes.Buffer
+			tree.print(&out)
+			if want := filepath.FromSlash(tt.want); out.String() != want {
+				t.Errorf("print() =\n%s\nwant\n%s", out.String(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package purge
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// isUnrealProjectName reports whether the file name belongs to an Unreal Engine project.
+func isUnrealProjectName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".uproject")
+}
+
+// removeUnrealCaches removes the regenerable directories of the Unreal Engine project of the file at path.
+// Compiling the binaries again takes long, so they are only removed with deep set.
+func removeUnrealCaches(path string, deep bool) error {
+	names := []string{"DerivedDataCache", "Intermediate", "Saved"}
+	if deep {
+		names = append(names, "Binaries")
+	}
+	for _, name := range names {
+		p := filepath.Join(filepath.Dir(path), name)
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
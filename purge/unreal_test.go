@@ -0,0 +1,53 @@
+package purge
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRemoveUnrealCaches(t *testing.T) {
+	files := []string{
+		"Game.uproject",
+		"Binaries/Win64/",
+		"Config/",
+		"Content/",
+		"DerivedDataCache/",
+		"Intermediate/Build/",
+		"Saved/Logs/",
+		"Source/",
+	}
+	tests := []struct {
+		name string
+		deep bool
+		want []string
+	}{
+		{"default", false, []string{"Binaries/", "Binaries/Win64/", "Config/", "Content/", "Game.uproject", "Source/"}},
+		{"deep", true, []string{"Config/", "Content/", "Game.uproject", "Source/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			if err := removeUnrealCaches(filepath.Join(dir, "Game.uproject"), tt.deep); err != nil {
+				t.Fatalf("removeUnrealCaches() = %v", err)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removeUnrealCaches() kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnrealProjectName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"Game.uproject": true,
+		"GAME.UPROJECT": true,
+		"Game.uplugin":  false,
+		"uproject":      false,
+	} {
+		if got := isUnrealProjectName(name); got != want {
+			t.Errorf("isUnrealProjectName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package purge
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// toolUsage is the reclaimable space of all matches of a single runner.
+type toolUsage struct {
+	name   string
+	dirs   int
+	size   int64
+	failed int // projects whose clean up failed, not part of dirs and size
+}
+
+// usageByTool groups the reclaimable space of all matches by runner.
+type usageByTool map[string]*toolUsage
+
+func (u usageByTool) add(name string, size int64) {
+	t, ok := u[name]
+	if !ok {
+		t = &toolUsage{name: name}
+		u[name] = t
+	}
+	t.dirs++
+	t.size += size
+}
+
+// fail counts a project of the runner, which failed to clean.
+func (u usageByTool) fail(name string) {
+	t, ok := u[name]
+	if !ok {
+		t = &toolUsage{name: name}
+		u[name] = t
+	}
+	t.failed++
+}
+
+// sorted returns the usage of all runners, the largest first.
+func (u usageByTool) sorted() []*toolUsage {
+	tools := make([]*toolUsage, 0, len(u))
+	for _, t := range u {
+		tools = append(tools, t)
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		if tools[i].size != tools[j].size {
+			return tools[i].size > tools[j].size
+		}
+		return tools[i].name < tools[j].name
+	})
+	return tools
+}
+
+// plural returns the singular form of a noun for a count of one and the plural form otherwise.
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+// print writes a line per runner, the largest first.
+func (u usageByTool) print(out io.Writer) {
+	for _, t := range u.sorted() {
+		fmt.Fprintf(out, "%s: %d %s, %s\n", t.name, t.dirs, plural(t.dirs, "dir", "dirs"), formatBytes(t.size))
+	}
+}
+
+// printSummary writes a line per runner with the number of cleaned projects - and their size, if it was measured.
+// The summary of an interrupted run ends with a note, it covers the projects processed so far only.
+func (u usageByTool) printSummary(out io.Writer, sized, interrupted bool) {
+	for _, t := range u.sorted() {
+		line := fmt.Sprintf("%s: %d %s", t.name, t.dirs, plural(t.dirs, "project", "projects"))
+		if sized {
+			line += ", " + formatBytes(t.size)
+		}
+		if t.failed > 0 {
+			line += fmt.Sprintf(", %d failed", t.failed)
+		}
+		fmt.Fprintln(out, line)
+	}
+	if interrupted {
+		fmt.Fprintln(out, "interrupted: the summary covers the projects processed so far")
+	}
+}
+
+// ageBucket counts the reclaimable directories last modified within an age range.
+type ageBucket struct {
+	label  string
+	maxAge time.Duration // exclusive upper bound, 0 means unbounded
+	dirs   int
+	size   int64
+}
+
+// ageHistogram groups the reclaimable space of all matches by the age of their projects.
+type ageHistogram []*ageBucket
+
+func newAgeHistogram() ageHistogram {
+	const day = 24 * time.Hour
+	return ageHistogram{
+		{label: "< 1 week", maxAge: 7 * day},
+		{label: "1-4 weeks", maxAge: 28 * day},
+		{label: "1-6 months", maxAge: 183 * day},
+		{label: "> 6 months"},
+	}
+}
+
+func (h ageHistogram) add(age time.Duration, size int64) {
+	for _, b := range h {
+		if b.maxAge == 0 || age < b.maxAge {
+			b.dirs++
+			b.size += size
+			return
+		}
+	}
+}
+
+// print writes a line per bucket, the most recent first.
+func (h ageHistogram) print(out io.Writer) {
+	for _, b := range h {
+		fmt.Fprintf(out, "%-10s  %5d %-4s  %10s\n", b.label, b.dirs, plural(b.dirs, "dir", "dirs"), formatBytes(b.size))
+	}
+}
@@ -0,0 +1,114 @@
+package purge
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPrintSummary(t *testing.T) {
+	results := usageByTool{}
+	results.add("npm", 2048)
+	results.add("npm", 1024)
+	results.add("cargo", 1<<20)
+	results.fail("cargo")
+	tests := []struct {
+		name        string
+		sized       bool
+		interrupted bool
+		want        string
+	}{
+		{"projects", false, false, "cargo: 1 project, 1 failed\nnpm: 2 projects\n"},
+		{"sizes", true, false, "cargo: 1 project, 1.0 MiB, 1 failed\nnpm: 2 projects, 3.0 KiB\n"},
+		{"interrupted", false, true, "cargo: 1 project, 1 failed\nnpm: 2 projects\ninterrupted: the summary covers the projects processed so far\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			results.printSummary(&out, tt.sized, tt.interrupted)
+			if out.String() != tt.want {
+				t.Errorf("printSummary() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkByTool(t *testing.T) {
+	sizes := map[string]int{
+		"web/node_modules/react/index.js":   3000,
+		"api/node_modules/express/index.js": 1000,
+		"docs/node_modules/vue/index.js":    100,
+		"cli/target/debug/cli":              2000,
+		"lib/target/debug/liblib.rlib":      3000,
+		"old/node_modules/.keep":            0,
+	}
+	files := []string{"web/package.json", "api/package.json", "docs/package.json", "old/package.json", "cli/Cargo.toml", "lib/Cargo.toml"}
+	for name := range sizes {
+		files = append(files, name)
+	}
+	dir, cleanup := testTree(t, files)
+	defer cleanup()
+	for name, size := range sizes {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := &walker{tasks: []Task{testDeps(), testTarget()}, root: dir, out: ioutil.Discard, maxDepth: -1, dry: true, byTool: usageByTool{}}
+	if err := w.walk(dir, 0); err != nil {
+		t.Fatalf("walk() = %v", err)
+	}
+	var out bytes.Buffer
+	w.byTool.print(&out)
+	// the largest first
+	want := "target: 2 dirs, 4.9 KiB\ndeps: 4 dirs, 4.0 KiB\n"
+	if out.String() != want {
+		t.Errorf("print() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestWalkAgeHistogram(t *testing.T) {
+	const day = 24 * time.Hour
+	projects := []struct {
+		name string
+		age  time.Duration // of the manifest
+		size int
+	}{
+		{"today", 0, 100},
+		{"recent", 6 * day, 200},
+		{"weeks", 8 * day, 400},
+		{"month", 27 * day, 800},
+		{"months", 29 * day, 1600},
+		{"ancient", 400 * day, 3200},
+	}
+	var files []string
+	for _, p := range projects {
+		files = append(files, p.name+"/package.json", p.name+"/node_modules/index.js")
+	}
+	dir, cleanup := testTree(t, files)
+	defer cleanup()
+	for _, p := range projects {
+		if err := ioutil.WriteFile(filepath.Join(dir, p.name, "node_modules", "index.js"), make([]byte, p.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(-p.age)
+		if err := os.Chtimes(filepath.Join(dir, p.name, "package.json"), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := &walker{tasks: []Task{testDeps()}, root: dir, out: ioutil.Discard, maxDepth: -1, dry: true, ages: newAgeHistogram()}
+	if err := w.walk(dir, 0); err != nil {
+		t.Fatalf("walk() = %v", err)
+	}
+	var out bytes.Buffer
+	w.ages.print(&out)
+	want := "< 1 week        2 dirs       300 B\n" +
+		"1-4 weeks       2 dirs     1.2 KiB\n" +
+		"1-6 months      1 dir      1.6 KiB\n" +
+		"> 6 months      1 dir      3.1 KiB\n"
+	if out.String() != want {
+		t.Errorf("print() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
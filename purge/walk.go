@@ -0,0 +1,617 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Walk walks all directories in the given path with a Breadth-First-Search approach
+// and cleans each matching directory.
+//
+// Assumptions:
+// If file matches are found, the associated funcs run before the remaining subdirectories are walked.
+//   --> Stop walking into the artifact directories of the matches, removed or not
+//
+// The path of each match is printed to stdout. Once ctx is done, the walk stops after the current project.
+// Like Run, Walk resets the package state first and waits for other runs and walks to finish.
+func Walk(ctx context.Context, path string, tasks []Task) error {
+	runMu.Lock()
+	defer runMu.Unlock()
+	// a root behind a symbolic link would put the whole tree outside of it
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	configure(Config{})
+	options = runnerOptions{root: path, cargoCleanMode: "full"}
+	w := &walker{ctx: ctx, tasks: tasks, root: path, out: stdout, maxDepth: -1}
+	if err := w.walk(path, 0); err != nil {
+		return err
+	}
+	if len(w.failures) > 0 {
+		return errorList(w.failures)
+	}
+	return nil
+}
+
+// walker carries the state of a single run over a directory tree.
+type walker struct {
+	ctx          context.Context // stops the walk once it is done, optional
+	tasks        []Task
+	root         string        // start path of the walk
+	out          io.Writer     // receives the emitted paths
+	dry          bool          // print what would happen only
+	realpath     bool          // resolve symbolic links in emitted paths
+	print0       bool          // terminate emitted paths with NUL instead of a newline
+	color        bool          // color emitted paths by their action, for terminals only
+	reinstall    bool          // reinstall dependencies after a successful clean up
+	cleanReports bool          // also remove test and coverage reports of matched projects
+	skipSubs     bool          // don't walk into git submodules
+	keepTracked  bool          // don't remove dependency directories committed to git
+	gitIdle      time.Duration // skip projects with more recent activity (0 disables the check)
+	since        time.Duration // skip projects whose manifest changed more recently (0 disables the check)
+	minSize      int64         // skip projects which would free less bytes (0 disables the check)
+	maxErrors    int           // abort once this many errors were recorded (0 means unlimited)
+	keepGoing    bool          // record failed tasks and unreadable directories instead of aborting the walk
+	limitPerTool int           // process at most this many matches per named task (0 means unlimited)
+	maxDepth     int           // don't descend below this many levels (negative means unlimited)
+	exclude      []string      // glob patterns of directories to skip, relative to root
+	include      []string      // glob patterns of the only projects to process, relative to root (all if empty)
+	rootMarkers  []string      // names of files or directories which mark a project root, which is not descended below
+	total        int           // number of directories to visit, if known from a pre-scan
+	visited      int           // number of visited directories
+	skipped      []skippedDir
+	failures     []error              // errors which did not abort the walk
+	commits      map[string]time.Time // last commit dates by repository root
+	submodules   map[string]bool      // paths of known git submodules
+	artifacts    map[string]bool      // paths of the artifact directories of all matches
+	processed    map[string]int       // number of processed matches by task name
+	tree         *sizeTree            // reclaimable space of all matches, if requested
+	byTool       usageByTool          // reclaimable space of all matches by runner, if requested
+	ages         ageHistogram         // reclaimable space of all matches by age, if requested
+	doc          *jsonDocument        // buffers emitted paths for a single JSON document, if requested
+	sizes        bool                 // measure the artifacts of each match before removal
+	measured     int64                // accumulated size of all measured artifacts
+	showSizes    bool                 // print the reclaimed size of each match
+	reclaimed    int64                // accumulated size printed with showSizes
+	jsonLines    bool                 // print a line of JSON per processed directory instead of its path
+	pool         *workerPool          // runs the tasks of matches concurrently, if set
+	results      usageByTool          // number and size of the cleaned projects by task name
+	status       *statusLine          // reports the progress periodically, if set
+	mu           sync.Mutex           // guards failures, results, reclaimed, visited and doc against concurrent workers
+}
+
+// fail records an error which does not abort the walk - unless the maximum number of errors is reached.
+func (w *walker) fail(err error) error {
+	w.status.clear(func() { fmt.Fprintln(stderr, err) })
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.failures = append(w.failures, err)
+	if w.maxErrors > 0 && len(w.failures) >= w.maxErrors {
+		return fmt.Errorf("aborting after reaching the maximum of %d %s", w.maxErrors, plural(w.maxErrors, "error", "errors"))
+	}
+	return nil
+}
+
+// restore reinstalls the dependencies of a cleaned project, if requested and supported by the task.
+// A failed reinstall is recorded, but doesn't stop the walk.
+func (w *walker) restore(task Task, path string) error {
+	r, ok := task.(reinstaller)
+	if !w.reinstall || !ok {
+		return nil
+	}
+	cmd := r.Reinstall(path)
+	if cmd == nil {
+		return nil
+	}
+	if w.dry {
+		fmt.Fprintf(stderr, "would run command %q in %s\n", cmd.String(), cmd.Dir)
+		return nil
+	}
+	cmd.Env = commandEnv
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return w.fail(&TaskError{Tool: task.Name(), Path: path, Err: fmt.Errorf("failed to run command %q in %s: %w\n%s", cmd.String(), cmd.Dir, err, string(out))})
+	}
+	return nil
+}
+
+// emit prints the path of a processed match.
+func (w *walker) emit(path string) {
+	if w.realpath {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+	}
+	if w.doc != nil {
+		w.mu.Lock()
+		w.doc.add(path)
+		w.mu.Unlock()
+		return
+	}
+	// the only output to stdout of this app is the full path of a processed match
+	if w.print0 {
+		w.status.clear(func() { fmt.Fprint(w.out, path, "\x00") })
+		return
+	}
+	if w.color {
+		path = colorize(path, w.dry)
+	}
+	w.status.clear(func() { fmt.Fprintln(w.out, path) })
+}
+
+// walk walks the directory at path, which is depth levels below the start path.
+func (w *walker) walk(path string, depth int) error {
+	if w.ctx != nil && w.ctx.Err() != nil {
+		return errInterrupted
+	}
+	entries, err := fileSystem.ReadDir(path)
+	if depth > 0 && errors.Is(err, os.ErrNotExist) {
+		// removed by a task of a parent directory in the meantime
+		return nil
+	}
+	if err != nil && depth > 0 && w.keepGoing {
+		return w.fail(fmt.Errorf("failed to read file entries of directory %q: %w", path, err))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file entries of directory %q: %w", path, err)
+	}
+	w.progress()
+	logf("entering %s", path)
+	// run all clean up tasks of this directory before walking into what is left of it
+	var jobs []func() error
+	for _, m := range w.match(path, entries) {
+		w.markArtifacts(path, m.task)
+		job, err := w.process(path, m.task, m.entry)
+		if err != nil {
+			return err
+		}
+		if job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+	if err := w.runAll(jobs); err != nil {
+		return err
+	}
+	if w.maxDepth >= 0 && depth >= w.maxDepth {
+		return nil
+	}
+	// the start path is often a project root itself
+	if marker := w.rootMarker(entries); depth > 0 && marker != "" {
+		logf("not descending below %s: project root marked by %s", path, marker)
+		return nil
+	}
+	if w.skipSubs {
+		w.markSubmodules(path, entries)
+	}
+	// loop over directories and walk into them
+	for _, entry := range entries {
+		// `file.IsDir()` check excludes strange files like symbolic links, device files or named pipes
+		// that's exactly what we need
+		if entry.IsDir() && w.artifacts[filepath.Join(path, entry.Name())] {
+			// removed already - or kept on purpose, e.g. in dry runs, but dependencies are no projects of their own
+			continue
+		} else if entry.IsDir() && w.submodules[filepath.Join(path, entry.Name())] {
+			w.skip(filepath.Join(path, entry.Name()), skipSubmodule)
+		} else if entry.IsDir() && w.excluded(filepath.Join(path, entry.Name())) {
+			w.skip(filepath.Join(path, entry.Name()), skipExcluded)
+		} else if entry.IsDir() {
+			// only directories need the checks, which resolve each path
+			if reason := w.unsafeDir(filepath.Join(path, entry.Name())); reason != "" {
+				w.skip(filepath.Join(path, entry.Name()), reason)
+				continue
+			}
+			if err := w.walk(filepath.Join(path, entry.Name()), depth+1); err != nil {
+				// don't wrap the error - at this point all error paths are already wrapped
+				return err
+			}
+		} else if entry.Mode()&os.ModeSymlink != 0 {
+			// only symbolic links pointing to a directory would have been walked into
+			dir := filepath.Join(path, entry.Name())
+			if info, err := fileSystem.Stat(dir); err == nil && info.IsDir() {
+				w.skip(dir, skipSymlink)
+			}
+		}
+	}
+	return nil
+}
+
+// taskMatch is a file - or a directory - matched by a task.
+type taskMatch struct {
+	task  Task
+	entry os.FileInfo
+}
+
+// match searches the files and directories of the directory at path for matching tasks.
+// Each entry is claimed by the first matching task, each task is matched at most once.
+func (w *walker) match(path string, entries []os.FileInfo) []taskMatch {
+	var matches []taskMatch
+	matched := map[int]bool{} // by index of the task
+	for _, entry := range entries {
+		for i, task := range w.tasks {
+			if entry.IsDir() {
+				// only tasks which match directories by name
+				if d, ok := task.(dirMatcher); !ok || !d.MatchesDir(entry.Name()) {
+					continue
+				}
+			} else if !task.Matches(entry.Name()) {
+				continue
+			}
+			// the file name alone is too generic for some tasks
+			if v, ok := task.(verifier); ok && !v.Verify(filepath.Join(path, entry.Name())) {
+				logf("skipping %s: not a project of runner %s", filepath.Join(path, entry.Name()), task.Name())
+				continue
+			}
+			if !matched[i] {
+				matched[i] = true
+				matches = append(matches, taskMatch{task: task, entry: entry})
+			}
+			break
+		}
+	}
+	return matches
+}
+
+// markArtifacts remembers the existing artifact directories of the task matched in the directory at path,
+// so the walk doesn't descend into them. Directories the task keeps are walked like any other.
+func (w *walker) markArtifacts(path string, task Task) {
+	l, ok := task.(artifactLister)
+	if !ok {
+		return
+	}
+	if w.artifacts == nil {
+		w.artifacts = map[string]bool{}
+	}
+	for _, artifact := range l.Artifacts() {
+		dir := filepath.Join(path, filepath.FromSlash(artifact))
+		if info, err := fileSystem.Lstat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if keepsArtifact(task, dir) {
+			logf("walking %s: kept by runner %s", dir, task.Name())
+			continue
+		}
+		w.artifacts[dir] = true
+	}
+}
+
+// process checks whether the task for the matched file in the directory at path should run
+// and returns the job which runs it - or nil, if the match is skipped.
+func (w *walker) process(path string, task Task, entry os.FileInfo) (func() error, error) {
+	if !w.included(path) {
+		w.skip(path, skipNotIncluded)
+		return nil, nil
+	}
+	if w.since > 0 && time.Since(entry.ModTime()) < w.since {
+		w.skip(path, skipTooRecent)
+		return nil, nil
+	}
+	if w.gitIdle > 0 && time.Since(w.lastActivity(path, entry.ModTime())) < w.gitIdle {
+		w.skip(path, skipTooRecent)
+		return nil, nil
+	}
+	name := task.Name()
+	if w.limitPerTool > 0 && name != "" && w.processed[name] >= w.limitPerTool {
+		w.skip(path, skipLimit)
+		return nil, nil
+	}
+	if w.keepTracked {
+		if tracked := trackedArtifact(path, task); tracked != "" {
+			fmt.Fprintf(stderr, "skipping %s: committed to git\n", tracked)
+			w.skip(path, skipTracked)
+			return nil, nil
+		}
+	}
+	if w.minSize > 0 {
+		size, err := reclaimableSize(path, task)
+		if err != nil {
+			return nil, w.fail(err)
+		}
+		if size < w.minSize {
+			logf("skipping %s: %s is below the minimum size", path, formatBytes(size))
+			w.skip(path, skipTooSmall)
+			return nil, nil
+		}
+	}
+	if w.doc != nil {
+		// the JSON document lists the project directory like the line delimited JSON
+		w.emit(path)
+	} else if !w.jsonLines {
+		w.emit(filepath.Join(path, entry.Name()))
+	}
+	var size int64
+	if w.measuring() {
+		var err error
+		if size, err = w.measure(path, task, entry.ModTime()); err != nil {
+			return nil, w.fail(err)
+		}
+	}
+	if w.processed == nil {
+		w.processed = map[string]int{}
+	}
+	// count before running, so the limit per tool holds with concurrent workers, too
+	w.processed[name]++
+	return func() error {
+		return w.run(path, task, entry, name, size)
+	}, nil
+}
+
+// measuring reports whether the size of each match is needed.
+func (w *walker) measuring() bool {
+	return w.tree != nil || w.byTool != nil || w.ages != nil || w.sizes || w.showSizes || w.jsonLines
+}
+
+// runAll runs the jobs of a single directory one after the other - on a worker of the pool, if any.
+// Tasks of the same project touch the same files, e.g. when measuring the whole project, so they never run concurrently.
+func (w *walker) runAll(jobs []func() error) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	all := func() error {
+		for _, job := range jobs {
+			if err := job(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if w.pool == nil {
+		return all()
+	}
+	return w.pool.submit(all)
+}
+
+// run runs the task for the matched file in the directory at path and everything that follows a clean up.
+// It runs on a worker of the pool, if any, so all shared state of the walker has to be guarded by mu.
+func (w *walker) run(path string, task Task, entry os.FileInfo, name string, size int64) error {
+	// external commands decide on their own what to remove, so compare the size of the whole project instead
+	before := int64(-1)
+	if c, ok := task.(commander); ok && c.Command() != "" && (w.showSizes || w.jsonLines) && !w.dry {
+		var err error
+		if before, err = dirSize(path); err != nil {
+			return w.fail(err)
+		}
+	}
+	if err := task.Run(filepath.Join(path, entry.Name())); err != nil {
+		err = &TaskError{Tool: name, Path: path, Err: err}
+		w.mu.Lock()
+		if w.results == nil {
+			w.results = usageByTool{}
+		}
+		w.results.fail(name)
+		w.mu.Unlock()
+		if w.keepGoing {
+			return w.fail(err)
+		}
+		return err
+	}
+	if before >= 0 {
+		after, err := dirSize(path)
+		if err != nil {
+			return w.fail(err)
+		}
+		size = before - after
+	}
+	w.mu.Lock()
+	if w.results == nil {
+		w.results = usageByTool{}
+	}
+	w.results.add(name, size)
+	w.mu.Unlock()
+	if w.showSizes {
+		fmt.Fprintf(stderr, "%10s  %s (%s)\n", formatBytes(size), path, name)
+		w.mu.Lock()
+		w.reclaimed += size
+		w.mu.Unlock()
+	}
+	if w.jsonLines {
+		if err := w.emitJSON(path, name, size); err != nil {
+			return fmt.Errorf("failed to write JSON: %w", err)
+		}
+	}
+	if w.cleanReports {
+		if err := w.removeReports(path); err != nil {
+			if w.keepGoing {
+				return w.fail(err)
+			}
+			return err
+		}
+	}
+	return w.restore(task, filepath.Join(path, entry.Name()))
+}
+
+// count returns the number of directories a walk of path, which is depth levels below the start path, is going to visit.
+func (w *walker) count(path string, depth int) (int, error) {
+	entries, err := fileSystem.ReadDir(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file entries of directory %q: %w", path, err)
+	}
+	n := 1
+	for _, m := range w.match(path, entries) {
+		w.markArtifacts(path, m.task)
+	}
+	if w.maxDepth >= 0 && depth >= w.maxDepth {
+		return n, nil
+	}
+	if depth > 0 && w.rootMarker(entries) != "" {
+		return n, nil
+	}
+	if w.skipSubs {
+		w.markSubmodules(path, entries)
+	}
+	for _, entry := range entries {
+		dir := filepath.Join(path, entry.Name())
+		if entry.IsDir() && !w.artifacts[dir] && !w.submodules[dir] && !w.excluded(dir) && w.unsafeDir(dir) == "" {
+			c, err := w.count(dir, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			n += c
+		}
+	}
+	return n, nil
+}
+
+// progress counts a visited directory and reports the completion percentage,
+// if the total number of directories is known.
+func (w *walker) progress() {
+	w.mu.Lock()
+	w.visited++
+	w.mu.Unlock()
+	if w.total == 0 {
+		return
+	}
+	// only report changes of the percentage to keep the output calm
+	if percent := w.visited * 100 / w.total; w.visited == 1 || percent != (w.visited-1)*100/w.total {
+		fmt.Fprintf(stderr, "\rscanned %d of %d %s (%d%%)", w.visited, w.total, plural(w.total, "directory", "directories"), percent)
+	}
+	if w.visited == w.total {
+		fmt.Fprintln(stderr)
+	}
+}
+
+// removeBrokenSymlinks removes all symbolic links below root whose target doesn't exist (anymore).
+// Valid links and links which can't be inspected are always kept.
+func (w *walker) removeBrokenSymlinks(root string) error {
+	return walkFS(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk path %q: %w", path, err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		if _, err := fileSystem.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err := w.record(path, "broken-symlinks"); err != nil {
+			return err
+		}
+		if w.dry {
+			return nil
+		}
+		if err := fileSystem.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove broken symbolic link %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// skipReason names the cause for leaving a directory alone.
+type skipReason string
+
+const (
+	skipSymlink     skipReason = "symlink"
+	skipTooRecent   skipReason = "too-recent"
+	skipSubmodule   skipReason = "submodule"
+	skipTracked     skipReason = "git-tracked"
+	skipLimit       skipReason = "limit-per-tool"
+	skipExcluded    skipReason = "excluded"
+	skipOutside     skipReason = "outside-root"
+	skipNotIncluded skipReason = "not-included"
+	skipTooSmall    skipReason = "too-small"
+)
+
+// unsafeDir returns the reason not to walk into the directory, if any:
+// symbolic links are never followed - even if a file system reports them as directories -
+// and nothing outside of the start path is walked, which protects against loops, too.
+func (w *walker) unsafeDir(dir string) skipReason {
+	if info, err := fileSystem.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return skipSymlink
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		// gone in the meantime, which the walk handles
+		return ""
+	}
+	root := strings.TrimSuffix(w.root, string(filepath.Separator)) + string(filepath.Separator)
+	if resolved != w.root && !strings.HasPrefix(resolved, root) {
+		return skipOutside
+	}
+	return ""
+}
+
+// excluded reports whether the directory matches one of the exclude patterns, relative to the start path.
+func (w *walker) excluded(dir string) bool {
+	return w.matchesAny(w.exclude, dir)
+}
+
+// included reports whether the project directory matches one of the include patterns, relative to the start path.
+// Without include patterns every project is included. Directories are still walked into either way,
+// so the patterns may name projects at any depth, while exclude patterns remove whole subtrees.
+func (w *walker) included(dir string) bool {
+	return len(w.include) == 0 || w.matchesAny(w.include, dir)
+}
+
+// rootMarker returns the name of the first entry which marks the directory as a project root, if any.
+func (w *walker) rootMarker(entries []os.FileInfo) string {
+	for _, entry := range entries {
+		for _, marker := range w.rootMarkers {
+			if entry.Name() == marker {
+				return marker
+			}
+		}
+	}
+	return ""
+}
+
+// matchesAny reports whether the directory matches one of the glob patterns, relative to the start path.
+func (w *walker) matchesAny(patterns []string, dir string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(w.root, dir)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(filepath.FromSlash(pattern), rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// skippedDir is a directory which was not walked into, along with the reason why.
+type skippedDir struct {
+	path   string
+	reason skipReason
+}
+
+func (w *walker) skip(path string, reason skipReason) {
+	// several tasks of the same directory are skipped for the same reason
+	if n := len(w.skipped); n > 0 && w.skipped[n-1] == (skippedDir{path: path, reason: reason}) {
+		return
+	}
+	logf("skipping %s: %s", path, reason)
+	w.skipped = append(w.skipped, skippedDir{path: path, reason: reason})
+}
+
+// printSkipped writes the skipped directories grouped by reason.
+func printSkipped(out io.Writer, skipped []skippedDir) {
+	if len(skipped) == 0 {
+		fmt.Fprintln(out, "no directories were skipped")
+		return
+	}
+	groups := map[skipReason][]string{}
+	reasons := []skipReason{}
+	for _, s := range skipped {
+		if _, ok := groups[s.reason]; !ok {
+			reasons = append(reasons, s.reason)
+		}
+		groups[s.reason] = append(groups[s.reason], s.path)
+	}
+	sort.Slice(reasons, func(i, j int) bool { return reasons[i] < reasons[j] })
+	fmt.Fprintf(out, "skipped %d %s:\n", len(skipped), plural(len(skipped), "directory", "directories"))
+	for _, reason := range reasons {
+		fmt.Fprintf(out, "  %s (%d):\n", reason, len(groups[reason]))
+		for _, path := range groups[reason] {
+			fmt.Fprintf(out, "    %s\n", path)
+		}
+	}
+}
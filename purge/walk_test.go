@@ -0,0 +1,913 @@
+package purge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRunner returns the runner of the registry with the given name.
+func testRunner(t *testing.T, name string) runner {
+	t.Helper()
+	for _, r := range registry {
+		if r.name == name {
+			return r
+		}
+	}
+	t.Fatalf("no runner %s", name)
+	return runner{}
+}
+
+// testDeps returns a runner which removes the node_modules directory next to each package.json.
+func testDeps() runner {
+	return runner{
+		name: "deps",
+		available: func() bool {
+			return true
+		},
+		matches: func(s string) bool {
+			return s == "package.json"
+		},
+		run: func(path string) error {
+			return removeAll(filepath.Join(filepath.Dir(path), "node_modules"))
+		},
+		artifacts: []string{"node_modules"},
+	}
+}
+
+// testTarget returns a runner which removes the target directory next to each Cargo.toml.
+func testTarget() runner {
+	return runner{
+		name: "target",
+		available: func() bool {
+			return true
+		},
+		matches: func(s string) bool {
+			return s == "Cargo.toml"
+		},
+		run: func(path string) error {
+			return removeAll(filepath.Join(filepath.Dir(path), "target"))
+		},
+		artifacts: []string{"target"},
+	}
+}
+
+// testVendor returns a runner which removes the vendor directory next to each composer.json.
+func testVendor() runner {
+	return runner{
+		name: "vendor",
+		available: func() bool {
+			return true
+		},
+		matches: func(s string) bool {
+			return s == "composer.json"
+		},
+		run: func(path string) error {
+			return removeAll(filepath.Join(filepath.Dir(path), "vendor"))
+		},
+		artifacts: []string{"vendor"},
+	}
+}
+
+// testSetenv sets the environment variable key to value, the returned function restores it.
+func testSetenv(t *testing.T, key, value string) func() {
+	t.Helper()
+	old, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// testTools replaces PATH with a directory of fake tools, which log their name and arguments
+// and run the given shell script afterwards, e.g. `exit 1`. It returns a function reading the log
+// and a function restoring PATH.
+func testTools(t *testing.T, tools map[string]string) (func() []string, func()) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "purge-tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(dir, "log")
+	for name, script := range tools {
+		contents := fmt.Sprintf("#!/bin/sh\necho \"%s $*\" >> %s\n%s\n", name, log, script)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0755); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	restore := testSetenv(t, "PATH", dir)
+	read := func() []string {
+		data, err := ioutil.ReadFile(log)
+		if err != nil {
+			return nil
+		}
+		return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	return read, func() {
+		restore()
+		os.RemoveAll(dir)
+	}
+}
+
+// testTree creates the files below a new temporary directory, which the returned function removes again.
+// Names ending with a slash are created as directories.
+func testTree(t *testing.T, files []string) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "purge-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if name[len(name)-1] == '/' {
+			err = os.MkdirAll(p, 0755)
+		} else if err = os.MkdirAll(filepath.Dir(p), 0755); err == nil {
+			err = ioutil.WriteFile(p, nil, 0644)
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// testFiles lists the paths below dir in lexical order, using slashes and a trailing slash for directories.
+func testFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if info.IsDir() {
+			name += "/"
+		}
+		files = append(files, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestMarkArtifacts(t *testing.T) {
+	tests := []struct {
+		name   string
+		runner string
+		files  []string
+		want   []string
+	}{
+		{"configured cmake build", "cmake", []string{"CMakeLists.txt", "build/CMakeCache.txt"}, []string{"build"}},
+		{"cmake sources in build", "cmake", []string{"CMakeLists.txt", "build/main.c", "cmake-build-debug/CMakeCache.txt"}, []string{"cmake-build-debug"}},
+		{"virtual environments", "python", []string{"setup.py", ".venv/pyvenv.cfg", "venv/pyvenv.cfg"}, []string{".venv", "venv"}},
+		{"python sources in venv", "python", []string{"setup.py", "venv/setup.py", ".venv/app.py"}, nil},
+		{"setuptools output", "python", []string{"setup.py", "build/lib/", ".eggs/"}, []string{"build", ".eggs"}},
+		{"build without setuptools", "python", []string{"pyproject.toml", "build/lib/"}, nil},
+		{"latex output", "latex", []string{"paper.tex", "out/paper.aux", "build/main.go"}, []string{"out"}},
+		{"missing artifacts", "webext", []string{"manifest.json", "dist"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, tt.files)
+			defer cleanup()
+			if tt.runner == "latex" {
+				if err := ioutil.WriteFile(filepath.Join(dir, "paper.tex"), []byte("\\documentclass{article}\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w := &walker{}
+			w.markArtifacts(dir, testRunner(t, tt.runner))
+			if len(w.artifacts) != len(tt.want) {
+				t.Errorf("markArtifacts() marked %v, want %v", w.artifacts, tt.want)
+			}
+			for _, name := range tt.want {
+				if !w.artifacts[filepath.Join(dir, name)] {
+					t.Errorf("markArtifacts() didn't mark %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestWalkRemovedArtifacts(t *testing.T) {
+	files := []string{
+		"package.json", "composer.json",
+		"node_modules/dep/package.json", "node_modules/dep/node_modules/x/",
+		"vendor/lib/composer.json", "vendor/lib/vendor/x/",
+		"web/package.json", "web/node_modules/x/",
+	}
+	tests := []struct {
+		name string
+		dry  bool
+		kept []string
+	}{
+		{"purge", false, []string{"composer.json", "package.json", "web/", "web/package.json"}},
+		{"dry run", true, []string{
+			"composer.json",
+			"node_modules/", "node_modules/dep/", "node_modules/dep/node_modules/", "node_modules/dep/node_modules/x/", "node_modules/dep/package.json",
+			"package.json",
+			"vendor/", "vendor/lib/", "vendor/lib/composer.json", "vendor/lib/vendor/", "vendor/lib/vendor/x/",
+			"web/", "web/node_modules/", "web/node_modules/x/", "web/package.json",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			var runs []string
+			var tasks []Task
+			for _, r := range []runner{testDeps(), testVendor()} {
+				run := r.run
+				r.run = func(path string) error {
+					rel, err := filepath.Rel(dir, path)
+					runs = append(runs, filepath.ToSlash(rel))
+					if err != nil || tt.dry {
+						return err
+					}
+					return run(path)
+				}
+				tasks = append(tasks, r)
+			}
+			w := &walker{tasks: tasks, root: dir, out: ioutil.Discard, maxDepth: -1, dry: tt.dry}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			// both projects of the root are cleaned, but neither walked into
+			if want := []string{"composer.json", "package.json", "web/package.json"}; !reflect.DeepEqual(runs, want) {
+				t.Errorf("walk() ran %q, want %q", runs, want)
+			}
+			if got := testFiles(t, dir); !reflect.DeepEqual(got, tt.kept) {
+				t.Errorf("walk() kept %q, want %q", got, tt.kept)
+			}
+		})
+	}
+}
+
+func TestWalkAllTasks(t *testing.T) {
+	dir, cleanup := testTree(t, []string{
+		"Cargo.toml", "composer.json", "package.json",
+		"node_modules/x/index.js", "target/debug/app", "vendor/autoload.php",
+	})
+	defer cleanup()
+	var out bytes.Buffer
+	w := &walker{tasks: []Task{testDeps(), testVendor(), testTarget()}, root: dir, out: &out, maxDepth: -1}
+	if err := w.walk(dir, 0); err != nil {
+		t.Fatalf("walk() = %v", err)
+	}
+	if got, want := testFiles(t, dir), []string{"Cargo.toml", "composer.json", "package.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walk() kept %q, want %q", got, want)
+	}
+	want := filepath.Join(dir, "Cargo.toml") + "\n" + filepath.Join(dir, "composer.json") + "\n" + filepath.Join(dir, "package.json") + "\n"
+	if out.String() != want {
+		t.Errorf("walk() printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestWalkResetsState(t *testing.T) {
+	dir, cleanup := testTree(t, []string{"README.md", "src/main.go"})
+	defer cleanup()
+	defer func(fs FileSystem, v io.Writer, o runnerOptions) { fileSystem, verbose, options = fs, v, o }(fileSystem, verbose, options)
+	defer func(out, errOut io.Writer) { stdout, stderr = out, errOut }(stdout, stderr)
+	// the state of an earlier run, which must not leak into the walk
+	fileSystem = newMemFS(dir, nil)
+	verbose = ioutil.Discard
+	options = runnerOptions{root: "/elsewhere", deep: true}
+	if err := Walk(context.Background(), dir, []Task{testDeps()}); err != nil {
+		t.Fatalf("Walk() = %v", err)
+	}
+	if _, ok := fileSystem.(osFileSystem); !ok {
+		t.Errorf("Walk() kept the file system %T", fileSystem)
+	}
+	if verbose != nil {
+		t.Errorf("Walk() kept the verbose writer")
+	}
+	if resolved, _ := filepath.EvalSymlinks(dir); options.root != resolved || options.deep {
+		t.Errorf("Walk() set options %+v, want the root %s only", options, resolved)
+	}
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string // relative to the link in the root
+		want   []skippedDir
+	}{
+		{"self-referential", "link", nil},
+		{"current directory", ".", []skippedDir{{path: "link", reason: skipSymlink}}},
+		{"parent directory", "..", []skippedDir{{path: "link", reason: skipSymlink}}},
+		{"sibling directory", "sub", []skippedDir{{path: "link", reason: skipSymlink}}},
+		{"file", "sub/file", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"sub/file"})
+			defer cleanup()
+			if err := os.Symlink(tt.target, filepath.Join(dir, "link")); err != nil {
+				t.Skip(err)
+			}
+			if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+				dir = resolved
+			}
+			w := &walker{root: dir, out: ioutil.Discard, maxDepth: -1}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if len(w.skipped) != len(tt.want) {
+				t.Fatalf("walk() skipped %v, want %v", w.skipped, tt.want)
+			}
+			for i, want := range tt.want {
+				want.path = filepath.Join(dir, want.path)
+				if w.skipped[i] != want {
+					t.Errorf("walk() skipped %v, want %v", w.skipped[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestWalkSkipped(t *testing.T) {
+	files := []string{
+		"app/package.json", "app/node_modules/x/",
+		"archived/old/package.json", "archived/old/node_modules/x/",
+		"lib/package.json", "lib/node_modules/",
+	}
+	tests := []struct {
+		name      string
+		configure func(w *walker)
+		want      []skippedDir
+	}{
+		{
+			name:      "nothing",
+			configure: func(w *walker) {},
+		},
+		{
+			name:      "excluded",
+			configure: func(w *walker) { w.exclude = []string{"archived/*"} },
+			want:      []skippedDir{{"archived/old", skipExcluded}},
+		},
+		{
+			name:      "not included",
+			configure: func(w *walker) { w.include = []string{"app"} },
+			want:      []skippedDir{{"archived/old", skipNotIncluded}, {"lib", skipNotIncluded}},
+		},
+		{
+			name:      "too recent",
+			configure: func(w *walker) { w.since = time.Hour },
+			want:      []skippedDir{{"app", skipTooRecent}, {"archived/old", skipTooRecent}, {"lib", skipTooRecent}},
+		},
+		{
+			name:      "limit per tool",
+			configure: func(w *walker) { w.limitPerTool = 2 },
+			want:      []skippedDir{{"lib", skipLimit}},
+		},
+		{
+			name:      "too small",
+			configure: func(w *walker) { w.minSize = 1 },
+			want:      []skippedDir{{"app", skipTooSmall}, {"archived/old", skipTooSmall}, {"lib", skipTooSmall}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			w := &walker{tasks: []Task{testDeps()}, root: dir, out: ioutil.Discard, maxDepth: -1}
+			tt.configure(w)
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			var want []skippedDir
+			for _, s := range tt.want {
+				want = append(want, skippedDir{filepath.Join(dir, filepath.FromSlash(s.path)), s.reason})
+			}
+			if !reflect.DeepEqual(w.skipped, want) {
+				t.Errorf("walk() skipped %v, want %v", w.skipped, want)
+			}
+		})
+	}
+}
+
+func TestWalkExclude(t *testing.T) {
+	files := []string{
+		"app/package.json", "app/node_modules/x/",
+		"archived/package.json", "archived/node_modules/x/",
+		"archived/old/package.json", "archived/old/node_modules/x/",
+		"archived/old/nested/package.json", "archived/old/nested/node_modules/x/",
+		"prototypes/demo/package.json", "prototypes/demo/node_modules/x/",
+	}
+	tests := []struct {
+		name    string
+		exclude []string
+		matched []string
+	}{
+		{"nothing", nil, []string{"app", "archived", "archived/old", "archived/old/nested", "prototypes/demo"}},
+		{"children", []string{"archived/*"}, []string{"app", "archived", "prototypes/demo"}},
+		{"accumulated", []string{"archived/*", "prototypes/demo"}, []string{"app", "archived"}},
+		{"no match", []string{"archived/*/nested/*"}, []string{"app", "archived", "archived/old", "archived/old/nested", "prototypes/demo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			var matched []string
+			deps := testDeps()
+			deps.run = func(path string) error {
+				rel, err := filepath.Rel(dir, filepath.Dir(path))
+				matched = append(matched, filepath.ToSlash(rel))
+				return err
+			}
+			w := &walker{tasks: []Task{deps}, root: dir, out: ioutil.Discard, maxDepth: -1, exclude: tt.exclude}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if !reflect.DeepEqual(matched, tt.matched) {
+				t.Errorf("walk() matched %q, want %q", matched, tt.matched)
+			}
+		})
+	}
+}
+
+func TestWalkInclude(t *testing.T) {
+	files := []string{
+		"apps/web/package.json", "apps/web/node_modules/x/",
+		"apps/legacy/package.json", "apps/legacy/node_modules/x/",
+		"apps/legacy/tools/package.json", "apps/legacy/tools/node_modules/x/",
+		"libs/ui/package.json", "libs/ui/node_modules/x/",
+	}
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		matched []string
+	}{
+		{"everything", nil, nil, []string{"apps/legacy", "apps/legacy/tools", "apps/web", "libs/ui"}},
+		{"included", []string{"apps/*"}, nil, []string{"apps/legacy", "apps/web"}},
+		{"several included", []string{"apps/web", "libs/*"}, nil, []string{"apps/web", "libs/ui"}},
+		{"included and excluded", []string{"apps/*"}, []string{"apps/legacy"}, []string{"apps/web"}},
+		{"excluded subtree", []string{"apps/*", "apps/*/tools"}, []string{"apps/legacy"}, []string{"apps/web"}},
+		{"excluded only", nil, []string{"apps/legacy"}, []string{"apps/web", "libs/ui"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			var matched []string
+			deps := testDeps()
+			deps.run = func(path string) error {
+				rel, err := filepath.Rel(dir, filepath.Dir(path))
+				matched = append(matched, filepath.ToSlash(rel))
+				return err
+			}
+			w := &walker{tasks: []Task{deps}, root: dir, out: ioutil.Discard, maxDepth: -1, include: tt.include, exclude: tt.exclude}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if !reflect.DeepEqual(matched, tt.matched) {
+				t.Errorf("walk() matched %q, want %q", matched, tt.matched)
+			}
+		})
+	}
+}
+
+func TestWalkSince(t *testing.T) {
+	files := []string{
+		"fresh/package.json", "fresh/node_modules/x/",
+		"recent/package.json", "recent/node_modules/x/",
+		"stale/package.json", "stale/node_modules/x/",
+	}
+	ages := map[string]time.Duration{
+		"fresh":  time.Hour,
+		"recent": 29 * 24 * time.Hour,
+		"stale":  60 * 24 * time.Hour,
+	}
+	tests := []struct {
+		name  string
+		since time.Duration
+		kept  []string
+	}{
+		{"disabled", 0, nil},
+		{"30 days", 720 * time.Hour, []string{"fresh", "recent"}},
+		{"a week", 7 * 24 * time.Hour, []string{"fresh"}},
+		{"a year", 365 * 24 * time.Hour, []string{"fresh", "recent", "stale"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			for name, age := range ages {
+				modTime := time.Now().Add(-age)
+				if err := os.Chtimes(filepath.Join(dir, name, "package.json"), modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w := &walker{tasks: []Task{testDeps()}, root: dir, out: ioutil.Discard, maxDepth: -1, since: tt.since}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			var kept []string
+			for _, name := range testFiles(t, dir) {
+				if strings.HasSuffix(name, "/node_modules/") {
+					kept = append(kept, strings.TrimSuffix(name, "/node_modules/"))
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("walk() kept the dependencies of %q, want %q", kept, tt.kept)
+			}
+			if len(w.skipped) != len(tt.kept) {
+				t.Errorf("walk() skipped %v, want %d projects", w.skipped, len(tt.kept))
+			}
+		})
+	}
+}
+
+func TestWalkMinSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string // external commands are measured by the size of the whole project
+		size    int
+		run     bool
+	}{
+		{"just under", "", 1023, false},
+		{"exactly", "", 1024, true},
+		{"just over", "", 1025, true},
+		{"command just under", "npm run clean", 1023, false},
+		{"command just over", "npm run clean", 1025, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"app/package.json"})
+			defer cleanup()
+			name := filepath.Join(dir, "app", "node_modules", "x", "index.js")
+			if tt.command != "" {
+				name = filepath.Join(dir, "app", "dist", "index.js")
+			}
+			if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(name, make([]byte, tt.size), 0644); err != nil {
+				t.Fatal(err)
+			}
+			run := false
+			deps := testDeps()
+			deps.command = tt.command
+			deps.run = func(path string) error {
+				run = true
+				return nil
+			}
+			w := &walker{tasks: []Task{deps}, root: dir, out: ioutil.Discard, maxDepth: -1, minSize: 1024}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if run != tt.run || len(w.skipped) == 0 != tt.run {
+				t.Errorf("walk() ran the task %v and skipped %v, want run %v", run, w.skipped, tt.run)
+			}
+		})
+	}
+}
+
+func TestWalkMaxErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxErrors int
+		runs      int
+		wantErr   bool
+	}{
+		{"unlimited", 0, 5, false},
+		{"below the maximum", 6, 5, false},
+		{"maximum reached", 3, 3, true},
+		{"first error", 1, 1, true},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	stderr = ioutil.Discard
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files []string
+			for i := 0; i < 5; i++ {
+				files = append(files, fmt.Sprintf("app%d/package.json", i))
+			}
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			runs := 0
+			failing := testDeps()
+			failing.run = func(path string) error {
+				runs++
+				return fmt.Errorf("read-only file system")
+			}
+			w := &walker{tasks: []Task{failing}, root: dir, out: ioutil.Discard, maxDepth: -1, keepGoing: true, maxErrors: tt.maxErrors}
+			err := w.walk(dir, 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("walk() = %v, want error %v", err, tt.wantErr)
+			}
+			msg := fmt.Sprintf("maximum of %d errors", tt.maxErrors)
+			if tt.maxErrors == 1 {
+				msg = "maximum of 1 error"
+			}
+			if err != nil && !strings.Contains(err.Error(), msg) {
+				t.Errorf("walk() = %v", err)
+			}
+			if runs != tt.runs || len(w.failures) != tt.runs {
+				t.Errorf("walk() ran %d tasks and recorded %d failures, want %d", runs, len(w.failures), tt.runs)
+			}
+		})
+	}
+}
+
+func TestWalkLimitPerTool(t *testing.T) {
+	files := []string{
+		"a/package.json", "a/node_modules/",
+		"b/package.json", "b/node_modules/",
+		"c/package.json", "c/node_modules/",
+		"d/Cargo.toml", "d/target/",
+		"e/Cargo.toml", "e/target/",
+	}
+	tests := []struct {
+		name  string
+		limit int
+		kept  []string
+	}{
+		{"unlimited", 0, nil},
+		{"one per tool", 1, []string{"b/node_modules", "c/node_modules", "e/target"}},
+		{"two per tool", 2, []string{"c/node_modules"}},
+		{"above the matches", 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			w := &walker{tasks: []Task{testDeps(), testTarget()}, root: dir, out: ioutil.Discard, maxDepth: -1, limitPerTool: tt.limit}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			var kept []string
+			for _, name := range files {
+				if strings.HasSuffix(name, "/") {
+					if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+						kept = append(kept, strings.TrimSuffix(name, "/"))
+					}
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("walk() kept %q, want %q", kept, tt.kept)
+			}
+		})
+	}
+}
+
+func TestWalkDepth(t *testing.T) {
+	files := []string{
+		"package.json", "node_modules/x/",
+		"app/package.json", "app/node_modules/x/",
+		"app/lib/package.json", "app/lib/node_modules/x/",
+	}
+	tests := []struct {
+		name     string
+		maxDepth int
+		kept     []string
+	}{
+		{"root only", 0, []string{"app/lib/node_modules/", "app/node_modules/"}},
+		{"one level", 1, []string{"app/lib/node_modules/"}},
+		{"unlimited", -1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			w := &walker{tasks: []Task{testDeps()}, root: dir, out: ioutil.Discard, maxDepth: tt.maxDepth}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			var kept []string
+			for _, name := range testFiles(t, dir) {
+				if strings.HasSuffix(name, "node_modules/") {
+					kept = append(kept, name)
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("walk() kept %q, want %q", kept, tt.kept)
+			}
+		})
+	}
+}
+
+func TestWalkRootMarkers(t *testing.T) {
+	files := []string{
+		"app/.git/", "app/package.json", "app/node_modules/x/",
+		"app/third_party/lib/package.json", "app/third_party/lib/node_modules/x/",
+		"tools/package.json", "tools/node_modules/x/",
+		"tools/sub/.hg/", "tools/sub/package.json", "tools/sub/node_modules/x/",
+		"tools/sub/vendored/package.json", "tools/sub/vendored/node_modules/x/",
+	}
+	tests := []struct {
+		name    string
+		markers []string
+		matched []string
+	}{
+		{"none", nil, []string{"app", "app/third_party/lib", "tools", "tools/sub", "tools/sub/vendored"}},
+		{"git", []string{".git"}, []string{"app", "tools", "tools/sub", "tools/sub/vendored"}},
+		{"several", []string{".git", ".hg"}, []string{"app", "tools", "tools/sub"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			var matched []string
+			deps := testDeps()
+			deps.run = func(path string) error {
+				rel, err := filepath.Rel(dir, filepath.Dir(path))
+				matched = append(matched, filepath.ToSlash(rel))
+				return err
+			}
+			w := &walker{tasks: []Task{deps}, root: dir, out: ioutil.Discard, maxDepth: -1, rootMarkers: tt.markers}
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if !reflect.DeepEqual(matched, tt.matched) {
+				t.Errorf("walk() matched %q, want %q", matched, tt.matched)
+			}
+		})
+	}
+}
+
+func TestWalkRootMarkerStart(t *testing.T) {
+	// the start path is often a project root itself
+	dir, cleanup := testTree(t, []string{".git/", "package.json", "packages/ui/package.json", "packages/ui/node_modules/x/"})
+	defer cleanup()
+	w := &walker{tasks: []Task{testDeps()}, root: dir, out: ioutil.Discard, maxDepth: -1, rootMarkers: []string{".git"}}
+	if err := w.walk(dir, 0); err != nil {
+		t.Fatalf("walk() = %v", err)
+	}
+	if got, want := testFiles(t, dir), []string{".git/", "package.json", "packages/", "packages/ui/", "packages/ui/package.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walk() kept %q, want %q", got, want)
+	}
+}
+
+func TestWalkCount(t *testing.T) {
+	files := []string{
+		"app/package.json", "app/node_modules/x/", "app/src/lib/",
+		"archived/old/package.json", "archived/old/node_modules/x/",
+		"go/go.mod", "go/.git/", "go/cmd/tool/",
+		"docs/",
+	}
+	tests := []struct {
+		name      string
+		configure func(w *walker)
+		want      int
+	}{
+		{"unlimited", func(w *walker) {}, 11},
+		{"depth", func(w *walker) { w.maxDepth = 1 }, 5},
+		{"excluded", func(w *walker) { w.exclude = []string{"archived"} }, 9},
+		{"root markers", func(w *walker) { w.rootMarkers = []string{".git"} }, 8},
+	}
+	defer func(out io.Writer) { stderr = out }(stderr)
+	stderr = ioutil.Discard
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, files)
+			defer cleanup()
+			w := &walker{tasks: []Task{testDeps()}, root: dir, out: ioutil.Discard, maxDepth: -1, dry: true}
+			tt.configure(w)
+			n, err := w.count(dir, 0)
+			if err != nil {
+				t.Fatalf("count() = %v", err)
+			}
+			w.total = n
+			if err := w.walk(dir, 0); err != nil {
+				t.Fatalf("walk() = %v", err)
+			}
+			if n != tt.want || w.visited != n {
+				t.Errorf("count() = %d and walk() visited %d directories, want %d", n, w.visited, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintSkipped(t *testing.T) {
+	tests := []struct {
+		name    string
+		skipped []skippedDir
+		want    string
+	}{
+		{"none", nil, "no directories were skipped\n"},
+		{"single", []skippedDir{{"/a", skipExcluded}}, "skipped 1 directory:\n  excluded (1):\n    /a\n"},
+		{
+			name:    "grouped by reason",
+			skipped: []skippedDir{{"/b", skipTooRecent}, {"/a", skipExcluded}, {"/c", skipTooRecent}},
+			want:    "skipped 3 directories:\n  excluded (1):\n    /a\n  too-recent (2):\n    /b\n    /c\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printSkipped(&out, tt.skipped)
+			if out.String() != tt.want {
+				t.Errorf("printSkipped() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEmit(t *testing.T) {
+	dir, cleanup := testTree(t, []string{"real/project/package.json"})
+	defer cleanup()
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(filepath.Join(dir, "real"), link); err != nil {
+		t.Skip(err)
+	}
+	manifest := filepath.Join(link, "project", "package.json")
+	tests := []struct {
+		name     string
+		realpath bool
+		print0   bool
+		want     string
+	}{
+		{"as walked", false, false, manifest + "\n"},
+		{"canonical", true, false, filepath.Join(dir, "real", "project", "package.json") + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := &walker{out: &out, realpath: tt.realpath, print0: tt.print0}
+			w.emit(manifest)
+			if out.String() != tt.want {
+				t.Errorf("emit() printed %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveBrokenSymlinks(t *testing.T) {
+	links := map[string]string{
+		"to-file":      "file",
+		"to-dir":       "dir",
+		"broken":       "missing",
+		"dir/broken":   "../missing",
+		"to-broken":    "broken",
+		"dir/relative": "../file",
+	}
+	tests := []struct {
+		name string
+		dry  bool
+	}{
+		{"remove", false},
+		{"dry run", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, []string{"file", "dir/"})
+			defer cleanup()
+			for name, target := range links {
+				if err := os.Symlink(filepath.FromSlash(target), filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+					t.Skip(err)
+				}
+			}
+			var out bytes.Buffer
+			w := &walker{out: &out, dry: tt.dry}
+			if err := w.removeBrokenSymlinks(dir); err != nil {
+				t.Fatalf("removeBrokenSymlinks() = %v", err)
+			}
+			broken := []string{"broken", "dir/broken", "to-broken"}
+			var want string
+			for _, name := range broken {
+				want += filepath.Join(dir, filepath.FromSlash(name)) + "\n"
+			}
+			if out.String() != want {
+				t.Errorf("removeBrokenSymlinks() printed %q, want %q", out.String(), want)
+			}
+			for name := range links {
+				_, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(name)))
+				isBroken := name == "broken" || name == "dir/broken" || name == "to-broken"
+				if removed := os.IsNotExist(err); removed != (isBroken && !tt.dry) {
+					t.Errorf("%s removed = %v", name, removed)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package purge
+
+import (
+	"encoding/json"
+)
+
+// webExtBuildDirs are the usual output directories of bundled browser extensions, removed with -clean-build-output only.
+var webExtBuildDirs = []string{"dist", "build"}
+
+// isWebExtensionManifest reports whether the manifest.json at path belongs to a browser extension.
+// Web app manifests and other lookalikes share the file name, but never declare a manifest version.
+func isWebExtensionManifest(path string) bool {
+	data, err := fileSystem.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var manifest struct {
+		ManifestVersion json.Number `json:"manifest_version"`
+		Name            string      `json:"name"`
+		Version         string      `json:"version"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return false
+	}
+	switch manifest.ManifestVersion {
+	case "2", "3":
+		return manifest.Name != "" && manifest.Version != ""
+	}
+	return false
+}
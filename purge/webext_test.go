@@ -0,0 +1,36 @@
+package purge
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsWebExtensionManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     bool
+	}{
+		{"manifest v2", `{"manifest_version": 2, "name": "Ad Blocker", "version": "1.2.0", "background": {"scripts": ["bg.js"]}}`, true},
+		{"manifest v3", `{"manifest_version": 3, "name": "__MSG_name__", "version": "0.1", "action": {}}`, true},
+		{"web app manifest", `{"name": "Shop", "short_name": "Shop", "start_url": "/", "display": "standalone", "icons": []}`, false},
+		{"asset manifest", `{"files": {"main.js": "/static/js/main.1a2b.js"}, "entrypoints": ["static/js/main.1a2b.js"]}`, false},
+		{"unknown manifest version", `{"manifest_version": 1, "name": "legacy", "version": "1.0"}`, false},
+		{"incomplete", `{"manifest_version": 3, "name": "test"}`, false},
+		{"invalid", `{"manifest_version": 3,`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := testTree(t, nil)
+			defer cleanup()
+			path := filepath.Join(dir, "manifest.json")
+			if err := ioutil.WriteFile(path, []byte(tt.manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := isWebExtensionManifest(path); got != tt.want {
+				t.Errorf("isWebExtensionManifest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package purge
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// xcodeDerivedData returns the directory which holds the build products and indexes of all Xcode projects.
+func xcodeDerivedData() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "Developer", "Xcode", "DerivedData"), nil
+}
+
+// clearCachesXcode removes the contents of Xcode's DerivedData directory.
+// Xcode exists on macOS only, so this is a no-op on all other platforms.
+func clearCachesXcode() error {
+	if runtime.GOOS != "darwin" {
+		return nil
+	}
+	dir, err := xcodeDerivedData()
+	if err != nil {
+		return err
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file entries of directory %q: %w", dir, err)
+	}
+	// keep the directory itself, Xcode expects it to exist
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// isXcodeProjectDir reports whether the directory name belongs to an Xcode project or workspace bundle.
+func isXcodeProjectDir(name string) bool {
+	return strings.HasSuffix(name, ".xcodeproj") || strings.HasSuffix(name, ".xcworkspace")
+}
+
+// removeXcodeBuild removes the build products of all Xcode projects and workspaces next to the bundle at path from DerivedData.
+// A directory often holds both, a project and a workspace of the same name, which are built into different entries.
+func removeXcodeBuild(path string) error {
+	if runtime.GOOS != "darwin" {
+		return nil
+	}
+	dir, err := xcodeDerivedData()
+	if err != nil {
+		return err
+	}
+	bundles, err := fileSystem.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to read file entries of directory %q: %w", filepath.Dir(path), err)
+	}
+	for _, bundle := range bundles {
+		if !bundle.IsDir() || !isXcodeProjectDir(bundle.Name()) {
+			continue
+		}
+		if err := removeDerivedData(dir, filepath.Join(filepath.Dir(path), bundle.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDerivedData removes the entries of the project or workspace at path from the DerivedData directory dir.
+// Xcode names each entry like the project with a hash appended and records the full path of the project
+// in the info.plist of the entry, which tells apart projects of the same name.
+func removeDerivedData(dir, path string) error {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	matches, err := globFS(filepath.Join(dir, name+"-*"))
+	if err != nil {
+		return fmt.Errorf("failed to find build products of %s: %w", path, err)
+	}
+	var escaped bytes.Buffer
+	if err := xml.EscapeText(&escaped, []byte(path)); err != nil {
+		return fmt.Errorf("failed to escape path %s: %w", path, err)
+	}
+	workspace := []byte("<string>" + escaped.String() + "</string>")
+	for _, p := range matches {
+		info, err := fileSystem.ReadFile(filepath.Join(p, "info.plist"))
+		if err != nil || !bytes.Contains(info, workspace) {
+			logf("skipping %s: not built from %s", p, path)
+			continue
+		}
+		if err := removeAll(p); err != nil {
+			return fmt.Errorf("failed to remove path %s: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package purge
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// testDerivedData creates a home directory with the DerivedData entry of the Xcode project App.xcodeproj
+// in the directory code/app and the entry of an unrelated project of the same name.
+func testDerivedData(t *testing.T) (string, func()) {
+	t.Helper()
+	home, cleanup := testTree(t, []string{
+		"code/app/App.xcodeproj/project.pbxproj",
+		"Library/Developer/Xcode/DerivedData/App-abc/Build/",
+		"Library/Developer/Xcode/DerivedData/App-def/Build/",
+	})
+	derived := filepath.Join(home, "Library", "Developer", "Xcode", "DerivedData")
+	for name, project := range map[string]string{
+		"App-abc": filepath.Join(home, "code", "app", "App.xcodeproj"),
+		"App-def": filepath.Join(home, "other", "App.xcodeproj"),
+	} {
+		info := "<plist><dict><key>WorkspacePath</key><string>" + project + "</string></dict></plist>"
+		if err := ioutil.WriteFile(filepath.Join(derived, name, "info.plist"), []byte(info), 0644); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	return home, cleanup
+}
+
+func TestRemoveDerivedData(t *testing.T) {
+	home, cleanup := testDerivedData(t)
+	defer cleanup()
+	derived := filepath.Join(home, "Library", "Developer", "Xcode", "DerivedData")
+	if err := removeDerivedData(derived, filepath.Join(home, "code", "app", "App.xcodeproj")); err != nil {
+		t.Fatalf("removeDerivedData() = %v", err)
+	}
+	want := []string{"App-def/", "App-def/Build/", "App-def/info.plist"}
+	if got := testFiles(t, derived); !reflect.DeepEqual(got, want) {
+		t.Errorf("removeDerivedData() kept %q, want %q", got, want)
+	}
+}
+
+func TestXcodeOtherPlatforms(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("Xcode exists on macOS")
+	}
+	tests := []struct {
+		name string
+		run  func(home string) error
+	}{
+		{"clear caches", func(home string) error { return clearCachesXcode() }},
+		{"remove build", func(home string) error {
+			return removeXcodeBuild(filepath.Join(home, "code", "app", "App.xcodeproj"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, cleanup := testDerivedData(t)
+			defer cleanup()
+			defer testSetenv(t, "HOME", home)()
+			before := testFiles(t, home)
+			if err := tt.run(home); err != nil {
+				t.Fatalf("run() = %v", err)
+			}
+			if got := testFiles(t, home); !reflect.DeepEqual(got, before) {
+				t.Errorf("run() changed %q to %q", before, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyInterrupt returns a context, which is canceled by the first interrupt: the current project is completed,
+// then the walk stops so the partial reports can be written. A second interrupt exits immediately.
+func notifyInterrupt() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		cancel()
+		<-c
+		os.Exit(errorExitCode)
+	}()
+	return ctx
+}
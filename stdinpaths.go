@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readPaths reads newline separated paths from r, e.g. the output of find. Blank lines are skipped.
+func readPaths(r io.Reader) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// keep surrounding spaces, which are valid in names, but drop the carriage return of Windows line endings
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read paths: %w", err)
+	}
+	return paths, nil
+}
+
+// resolveRoot returns the absolute and clean path of the directory to walk.
+// The given path is followed even if it is a symbolic link, links within the tree are not.
+func resolveRoot(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse given path %s: %w", path, err)
+	}
+	if info, err := os.Stat(absPath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("path does not exist or is not a directory: %s", absPath)
+	}
+	if absPath, err = filepath.EvalSymlinks(absPath); err != nil {
+		return "", fmt.Errorf("failed to resolve given path %s: %w", path, err)
+	}
+	return absPath, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "purge-root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "code"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(filepath.Join(dir, "code"), link); err != nil {
+		link = ""
+	}
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"directory", filepath.Join(dir, "code"), filepath.Join(dir, "code"), false},
+		{"unclean path", filepath.Join(dir, "code") + "/../code/", filepath.Join(dir, "code"), false},
+		{"missing", filepath.Join(dir, "missing"), "", true},
+		{"file", filepath.Join(dir, "file"), "", true},
+		{"symbolic link", link, filepath.Join(dir, "code"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.path == "" {
+				t.Skip("symbolic links are not supported")
+			}
+			got, err := resolveRoot(tt.path)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("resolveRoot() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "purge-root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"missing", filepath.Join(dir, "missing")},
+		{"file", file},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runMain(t, "", "-dry", tt.path)
+			if code != errorParseExitCode {
+				t.Errorf("exit code = %d, want %d", code, errorParseExitCode)
+			}
+			if stdout != "" || !strings.Contains(stderr, "path does not exist or is not a directory") {
+				t.Errorf("printed %q and %q", stdout, stderr)
+			}
+		})
+	}
+}
+
+func TestReadPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"several", "/code/a\n/code/b\n/code/c\n", []string{"/code/a", "/code/b", "/code/c"}},
+		{"blank lines", "\n/code/a\n\n  \n/code/b", []string{"/code/a", "/code/b"}},
+		{"windows line endings", "C:\\code\\a\r\nC:\\code\\b\r\n", []string{"C:\\code\\a", "C:\\code\\b"}},
+		{"spaces in names", " /code/my app \n", []string{" /code/my app "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readPaths(bytes.NewReader([]byte(tt.in)))
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readPaths(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdinPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "purge-stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	var paths, want []string
+	for _, name := range []string{"a", "b"} {
+		ext := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Join(ext, "web-ext-artifacts"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		manifest := `{"manifest_version": 2, "name": "` + name + `", "version": "1.0"}`
+		if err := ioutil.WriteFile(filepath.Join(ext, "manifest.json"), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, ext, "")
+		want = append(want, filepath.Join(ext, "manifest.json"))
+	}
+	// a missing path is reported, but doesn't stop the others
+	paths = append([]string{filepath.Join(dir, "missing")}, paths...)
+	stdout, stderr, code := runMain(t, strings.Join(paths, "\n"), "-stdin", "-yes", "-skip-cache", "-tools", "webext")
+	if code != errorExitCode {
+		t.Errorf("exit code = %d, want %d\n%s", code, errorExitCode, stderr)
+	}
+	if got := strings.Fields(stdout); !reflect.DeepEqual(got, want) {
+		t.Errorf("purged %q, want %q", got, want)
+	}
+	if !strings.Contains(stderr, filepath.Join(dir, "missing")) {
+		t.Errorf("stderr = %q, want the missing path", stderr)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// storageKind classifies the storage of a path by how it copes with concurrent removals.
+type storageKind string
+
+const (
+	storageUnknown storageKind = "unknown"
+	storageSSD     storageKind = "ssd"
+	storageHDD     storageKind = "hdd"
+	storageNetwork storageKind = "network"
+)
+
+// networkFileSystems are the types of mounts backed by a remote server.
+var networkFileSystems = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smb3": true, "smbfs": true, "9p": true, "afs": true,
+	"ceph": true, "glusterfs": true, "lustre": true, "fuse.sshfs": true, "fuse.rclone": true, "davfs": true,
+}
+
+// jobs returns the number of projects to clean concurrently on the storage.
+func (k storageKind) jobs() int {
+	switch k {
+	case storageHDD:
+		// concurrent removals make the heads seek back and forth
+		return 1
+	case storageNetwork:
+		// each request waits for the server, more requests in flight hide the latency
+		return 2 * runtime.NumCPU()
+	}
+	return runtime.NumCPU()
+}
+
+// mount is an entry of /proc/self/mountinfo.
+type mount struct {
+	device string // major:minor
+	point  string
+	fsType string
+}
+
+// mountOf returns the mount containing path from the contents of /proc/self/mountinfo.
+// Of several mounts on the same point the last one is visible.
+func mountOf(path string, mountinfo []byte) (mount, bool) {
+	var found mount
+	var ok bool
+	unescape := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+	s := bufio.NewScanner(bytes.NewReader(mountinfo))
+	for s.Scan() {
+		// e.g. `36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3 /dev/root rw,errors=continue`
+		fields := strings.Fields(s.Text())
+		sep := -1
+		for i, f := range fields {
+			if f == "-" && i >= 6 {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || sep+1 >= len(fields) {
+			continue
+		}
+		m := mount{device: fields[2], point: unescape.Replace(fields[4]), fsType: fields[sep+1]}
+		if !isBelow(path, m.point) || (ok && len(m.point) < len(found.point)) {
+			continue
+		}
+		found, ok = m, true
+	}
+	return found, ok
+}
+
+// isBelow reports whether path is dir or lies below it.
+func isBelow(path, dir string) bool {
+	return path == dir || dir == "/" || strings.HasPrefix(path, dir+"/")
+}
+
+// classifyStorage classifies the storage of path from the contents of /proc/self/mountinfo
+// and the block devices of the sysfs mounted at sys.
+func classifyStorage(path string, mountinfo []byte, sys string) storageKind {
+	m, ok := mountOf(path, mountinfo)
+	if !ok {
+		return storageUnknown
+	}
+	if networkFileSystems[m.fsType] {
+		return storageNetwork
+	}
+	// e.g. /sys/dev/block/8:1 links to /sys/devices/.../block/sda/sda1
+	dev, err := filepath.EvalSymlinks(filepath.Join(sys, "dev", "block", m.device))
+	if err != nil {
+		// e.g. the anonymous devices of btrfs subvolumes and overlay mounts
+		return storageUnknown
+	}
+	// partitions have no queue of their own, it belongs to the disk one level up
+	for _, queue := range []string{filepath.Join(dev, "queue"), filepath.Join(filepath.Dir(dev), "queue")} {
+		rotational, err := ioutil.ReadFile(filepath.Join(queue, "rotational"))
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(rotational)) == "1" {
+			return storageHDD
+		}
+		return storageSSD
+	}
+	return storageUnknown
+}
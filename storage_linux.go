@@ -0,0 +1,12 @@
+package main
+
+import "io/ioutil"
+
+// storageOf classifies the storage of path by its mount and block device.
+func storageOf(path string) storageKind {
+	mountinfo, err := ioutil.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return storageUnknown
+	}
+	return classifyStorage(path, mountinfo, "/sys")
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+// storageOf doesn't classify storage on this platform.
+func storageOf(path string) storageKind {
+	return storageUnknown
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// testMountinfo mounts a partition of a hard disk at /, an SSD at /data and below it a network share and an overlay.
+const testMountinfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+23 22 259:0 / /data rw,relatime shared:2 - xfs /dev/nvme0n1 rw
+24 23 0:50 / /data/share rw,relatime shared:3 - nfs4 server:/export rw,vers=4.2
+25 23 0:51 / /data/my\040containers rw,relatime - overlay overlay rw
+26 22 8:1 / /broken rw,relatime
+`
+
+// newTestSys creates a sysfs with a hard disk sda with the partition sda1 and an SSD nvme0n1.
+func newTestSys(t *testing.T) (string, func()) {
+	t.Helper()
+	sys, err := ioutil.TempDir("", "purge-sys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"devices/pci0000:00/block/sda/queue/rotational":     "1\n",
+		"devices/pci0000:00/block/sda/sda1/partition":       "1\n",
+		"devices/pci0000:00/block/nvme0n1/queue/rotational": "0\n",
+	}
+	for name, contents := range files {
+		p := filepath.Join(sys, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"8:1":   "../../devices/pci0000:00/block/sda/sda1",
+		"259:0": "../../devices/pci0000:00/block/nvme0n1",
+	}
+	if err := os.MkdirAll(filepath.Join(sys, "dev", "block"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for dev, target := range links {
+		if err := os.Symlink(filepath.FromSlash(target), filepath.Join(sys, "dev", "block", dev)); err != nil {
+			os.RemoveAll(sys)
+			t.Skip(err)
+		}
+	}
+	return sys, func() { os.RemoveAll(sys) }
+}
+
+func TestClassifyStorage(t *testing.T) {
+	sys, cleanup := newTestSys(t)
+	defer cleanup()
+	tests := []struct {
+		name      string
+		path      string
+		mountinfo string
+		want      storageKind
+	}{
+		{"partition of a hard disk", "/home/luke/code", testMountinfo, storageHDD},
+		{"ssd", "/data/code", testMountinfo, storageSSD},
+		{"mount point", "/data", testMountinfo, storageSSD},
+		{"sibling of a mount point", "/database", testMountinfo, storageHDD},
+		{"network share", "/data/share/code", testMountinfo, storageNetwork},
+		{"escaped mount point", "/data/my containers/code", testMountinfo, storageUnknown},
+		{"malformed entry", "/broken/code", testMountinfo, storageHDD},
+		{"no mounts", "/home/luke/code", "", storageUnknown},
+		{"over-mounted", "/data/code", testMountinfo + "27 22 0:60 / /data rw - nfs server:/data rw\n", storageNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyStorage(tt.path, []byte(tt.mountinfo), sys); got != tt.want {
+				t.Errorf("classifyStorage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorageJobs(t *testing.T) {
+	tests := []struct {
+		kind storageKind
+		want int
+	}{
+		{storageSSD, runtime.NumCPU()},
+		{storageHDD, 1},
+		{storageNetwork, 2 * runtime.NumCPU()},
+		{storageUnknown, runtime.NumCPU()},
+	}
+	for _, tt := range tests {
+		if got := tt.kind.jobs(); got != tt.want {
+			t.Errorf("%s.jobs() = %d, want %d", tt.kind, got, tt.want)
+		}
+	}
+}
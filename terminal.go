@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"runtime"
+)
+
+// useColor reports whether printed paths should be colored: only if stdout is a terminal
+// and neither -no-color nor the NO_COLOR environment variable (see https://no-color.org) ask otherwise.
+// The console of Windows doesn't interpret escape sequences by default, so it never gets colors.
+func useColor(noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || runtime.GOOS == "windows" {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
+
+// isTerminal reports whether f is a terminal (or another character device).
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestIsTerminal(t *testing.T) {
+	file, err := ioutil.TempFile("", "purge-terminal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	tests := []struct {
+		name string
+		f    *os.File
+	}{
+		{"redirected to a file", file},
+		{"piped", w},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// progress lines and colors are for terminals only
+			if isTerminal(tt.f) {
+				t.Errorf("isTerminal() = true")
+			}
+		})
+	}
+}